@@ -0,0 +1,142 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+var (
+	// ErrInvalidScopeVars is returned when the scope variables aren't a struct or map
+	ErrInvalidScopeVars = errors.New("scope variables must encode to a json object")
+)
+
+// SigninLevel is the level a user signed in at
+type SigninLevel string
+
+const (
+	SigninLevelRoot      SigninLevel = "root"
+	SigninLevelNamespace SigninLevel = "namespace"
+	SigninLevelDatabase  SigninLevel = "database"
+)
+
+// SigninResult is returned when signing in as a root, namespace or database user
+type SigninResult struct {
+	Level SigninLevel
+	// Token is empty on versions of SurrealDB which don't issue tokens for system users
+	Token string
+
+	TokenData
+}
+
+// SigninRoot signs in as a root user
+func (db *DB) SigninRoot(user string, password string) (*SigninResult, error) {
+	return db.SigninRootCtx(context.Background(), user, password)
+}
+
+func (db *DB) SigninRootCtx(ctx context.Context, user string, password string) (*SigninResult, error) {
+	return db.signinSystemUser(ctx, SigninLevelRoot, UserInfo{User: user, Password: password})
+}
+
+// SigninNamespace signs in as a user defined on the namespace
+func (db *DB) SigninNamespace(ns string, user string, password string) (*SigninResult, error) {
+	return db.SigninNamespaceCtx(context.Background(), ns, user, password)
+}
+
+func (db *DB) SigninNamespaceCtx(ctx context.Context, ns string, user string, password string) (*SigninResult, error) {
+	return db.signinSystemUser(ctx, SigninLevelNamespace, UserInfo{User: user, Password: password, Namespace: ns})
+}
+
+// SigninDatabase signs in as a user defined on the database
+func (db *DB) SigninDatabase(ns string, database string, user string, password string) (*SigninResult, error) {
+	return db.SigninDatabaseCtx(context.Background(), ns, database, user, password)
+}
+
+func (db *DB) SigninDatabaseCtx(ctx context.Context, ns string, database string, user string, password string) (*SigninResult, error) {
+	return db.signinSystemUser(ctx, SigninLevelDatabase, UserInfo{User: user, Password: password, Namespace: ns, Database: database})
+}
+
+func (db *DB) signinSystemUser(ctx context.Context, level SigninLevel, vars UserInfo) (*SigninResult, error) {
+	result, err := db.send(ctx, "signin", vars)
+	if err != nil {
+		return nil, err
+	}
+
+	signin := &SigninResult{Level: level}
+
+	if token, ok := result.ResultString(); ok && token != "" {
+		signin.Token = token
+		if signin.TokenData, err = (TokenData{}).FromToken(token); err != nil {
+			return nil, err
+		}
+	}
+
+	return signin, nil
+}
+
+// SigninScope signs in as a scope user, vars can be a struct or map with the variables the scope expects
+// For example, a scope using $email, $password and $tenant:
+//
+//	surrealdb.SigninScope(db, "test", "test", "account", map[string]any{"email": email, "password": password, "tenant": "acme"})
+func SigninScope[V any](db *DB, ns string, database string, scope string, vars V) (*AuthenticationResult, error) {
+	return SigninScopeCtx(context.Background(), db, ns, database, scope, vars)
+}
+
+// SigninScopeCtx is the same as SigninScope, but the request will be cancelled when ctx is done
+func SigninScopeCtx[V any](ctx context.Context, db *DB, ns string, database string, scope string, vars V) (*AuthenticationResult, error) {
+	return scopeAuth(ctx, db, "signin", ns, database, scope, vars)
+}
+
+// SignupScope signs up a new scope user, vars can be a struct or map with the variables the scope expects
+func SignupScope[V any](db *DB, ns string, database string, scope string, vars V) (*AuthenticationResult, error) {
+	return SignupScopeCtx(context.Background(), db, ns, database, scope, vars)
+}
+
+// SignupScopeCtx is the same as SignupScope, but the request will be cancelled when ctx is done
+func SignupScopeCtx[V any](ctx context.Context, db *DB, ns string, database string, scope string, vars V) (*AuthenticationResult, error) {
+	return scopeAuth(ctx, db, "signup", ns, database, scope, vars)
+}
+
+func scopeAuth[V any](ctx context.Context, db *DB, method string, ns string, database string, scope string, vars V) (*AuthenticationResult, error) {
+	db, err := orDefault(db)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := scopeVars(db.codec, ns, database, scope, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	authResult := &AuthenticationResult{Success: false}
+	result, err := db.send(ctx, method, params)
+	if err != nil {
+		return authResult, err
+	}
+
+	err = authResult.fromQuery(result)
+
+	return authResult, err
+}
+
+// scopeVars merges the NS/DB/SC keys into the variables
+func scopeVars[V any](codec Config.Codec, ns string, database string, scope string, vars V) (map[string]any, error) {
+	data, err := codec.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]any{}
+	if string(data) != "null" {
+		if err := codec.Unmarshal(data, &params); err != nil {
+			return nil, ErrInvalidScopeVars
+		}
+	}
+
+	params["NS"] = ns
+	params["DB"] = database
+	params["SC"] = scope
+
+	return params, nil
+}
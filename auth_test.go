@@ -0,0 +1,119 @@
+package surrealdb_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	"github.com/test-go/testify/require"
+)
+
+type accountVars struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Tenant   string `json:"tenant"`
+}
+
+// signinParams returns the variables of every signin/signup request
+func signinParams(t *testing.T, mock *mockServer) []map[string]any {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	var params []map[string]any
+	for _, req := range mock.requests[0] {
+		if req.Method != "signin" && req.Method != "signup" {
+			continue
+		}
+
+		var vars map[string]any
+		require.NoError(t, json.Unmarshal(req.Params[0], &vars))
+		params = append(params, vars)
+	}
+
+	return params
+}
+
+func TestSigninScope_MergesVariables(t *testing.T) {
+	token := testToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix(), "sc": "account", "id": "user:1"})
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return token, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := surrealdb.SigninScope(db, "test", "app", "account", accountVars{Email: "sam@example.com", Password: "secret", Tenant: "acme"})
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, token, result.Token)
+	require.Equal(t, "user:1", result.Id)
+
+	result, err = surrealdb.SignupScope(db, "test", "app", "account", map[string]any{"email": "kim@example.com", "password": "secret"})
+	require.NoError(t, err)
+	require.True(t, result.Success)
+
+	require.Equal(t, []map[string]any{
+		{"NS": "test", "DB": "app", "SC": "account", "email": "sam@example.com", "password": "secret", "tenant": "acme"},
+		{"NS": "test", "DB": "app", "SC": "account", "email": "kim@example.com", "password": "secret"},
+	}, signinParams(t, mock))
+
+	_, err = surrealdb.SigninScope(db, "test", "app", "account", []string{"not", "an", "object"})
+	require.Equal(t, surrealdb.ErrInvalidScopeVars, err)
+}
+
+func TestUserInfo_CustomFieldNames(t *testing.T) {
+	data, err := json.Marshal(surrealdb.UserInfo{
+		User:          "sam@example.com",
+		Password:      "secret",
+		Scope:         "account",
+		UserField:     "email",
+		PasswordField: "password",
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"email":"sam@example.com","password":"secret","SC":"account"}`, string(data))
+
+	data, err = json.Marshal(surrealdb.UserInfo{User: "root", Password: "root"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"user":"root","pass":"root"}`, string(data))
+}
+
+func TestDB_SigninSystemUsers(t *testing.T) {
+	token := testToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix(), "ns": "test", "db": "app"})
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		var vars map[string]any
+		_ = json.Unmarshal(req.Params[0], &vars)
+
+		// pretend to be an older version, which doesn't issue tokens for root users
+		if _, ok := vars["NS"]; !ok {
+			return nil, ""
+		}
+		return token, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	root, err := db.SigninRoot("root", "root")
+	require.NoError(t, err)
+	require.Equal(t, surrealdb.SigninLevelRoot, root.Level)
+	require.Empty(t, root.Token)
+
+	ns, err := db.SigninNamespace("test", "admin", "secret")
+	require.NoError(t, err)
+	require.Equal(t, surrealdb.SigninLevelNamespace, ns.Level)
+	require.Equal(t, token, ns.Token)
+
+	database, err := db.SigninDatabase("test", "app", "admin", "secret")
+	require.NoError(t, err)
+	require.Equal(t, surrealdb.SigninLevelDatabase, database.Level)
+	require.Equal(t, "app", database.Database)
+
+	require.Equal(t, []map[string]any{
+		{"user": "root", "pass": "root"},
+		{"user": "admin", "pass": "secret", "NS": "test"},
+		{"user": "admin", "pass": "secret", "NS": "test", "DB": "app"},
+	}, signinParams(t, mock))
+}
@@ -0,0 +1,113 @@
+package surrealdb_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+type benchmarkRecord struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Email string   `json:"email"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score"`
+}
+
+// newBenchmarkServer answers every request with the same pre-encoded query result, so the
+// benchmarks only measure the allocations made by the client
+func newBenchmarkServer(b *testing.B, records int) *Config.DbConfig {
+	result := make([]benchmarkRecord, records)
+	for i := range result {
+		result[i] = benchmarkRecord{
+			ID:    fmt.Sprintf("user:%d", i),
+			Name:  fmt.Sprintf("User %d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+			Tags:  []string{"one", "two", "three"},
+			Score: float64(i) / 3,
+		}
+	}
+
+	encoded, err := json.Marshal([]map[string]any{{"result": result, "status": "OK", "time": "1ms"}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	suffix := append(append([]byte(`","result":`), encoded...), '}')
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var req mockRequest
+			if err := json.Unmarshal(data, &req); err != nil {
+				return
+			}
+
+			w, err := conn.NextWriter(websocket.TextMessage)
+			if err != nil {
+				return
+			}
+			w.Write([]byte(`{"id":"`))
+			w.Write([]byte(req.ID))
+			w.Write(suffix)
+			if err := w.Close(); err != nil {
+				return
+			}
+		}
+	}))
+	b.Cleanup(server.Close)
+
+	b.SetBytes(int64(len(encoded)))
+
+	return &Config.DbConfig{Url: "ws" + strings.TrimPrefix(server.URL, "http") + "/rpc"}
+}
+
+func benchmarkQuery(b *testing.B, records int) {
+	db, err := surrealdb.New(newBenchmarkServer(b, records))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	params := map[string]any{"name": "bench"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		result := surrealdb.QueryOn[benchmarkRecord](db, "SELECT * FROM user WHERE name = $name", params)
+		if result.Error() != nil {
+			b.Fatal(result.Error())
+		}
+		if len(result.All()) != records {
+			b.Fatalf("expected %d records, got %d", records, len(result.All()))
+		}
+	}
+}
+
+// BenchmarkQuery_Small a typical response with a single record
+func BenchmarkQuery_Small(b *testing.B) {
+	benchmarkQuery(b, 1)
+}
+
+// BenchmarkQuery_10MB a bulk export sized response, roughly 10MB of json
+func BenchmarkQuery_10MB(b *testing.B) {
+	benchmarkQuery(b, 95_000)
+}
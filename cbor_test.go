@@ -0,0 +1,275 @@
+package surrealdb_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+	"github.com/test-go/testify/require"
+)
+
+type cborAccount struct {
+	ID       surrealdb.RecordID `json:"id"`
+	Owner    surrealdb.RecordID `json:"owner"`
+	Created  time.Time          `json:"created"`
+	Lifetime surrealdb.Duration `json:"lifetime"`
+	Balance  surrealdb.Decimal  `json:"balance"`
+	Name     string             `json:"name"`
+}
+
+// cborServer answers every request with result, encoded as cbor like SurrealDB does
+type cborServer struct {
+	server *httptest.Server
+
+	lock sync.Mutex
+	// raw holds the cbor of every request, requests holds the same requests as json
+	raw      [][]byte
+	requests []mockRequest
+}
+
+func newCborServer(t *testing.T, subprotocols []string, result any) *cborServer {
+	mock := &cborServer{}
+
+	upgrader := websocket.Upgrader{Subprotocols: subprotocols}
+	mock.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if kind != websocket.BinaryMessage {
+				t.Errorf("expected a binary message, got %d", kind)
+				return
+			}
+
+			var decoded bytes.Buffer
+			if err := internal.CBORToJSON(&decoded, data); err != nil {
+				t.Errorf("mock server failed to decode request: %s", err)
+				return
+			}
+
+			var req mockRequest
+			if err := json.Unmarshal(decoded.Bytes(), &req); err != nil {
+				t.Errorf("mock server failed to decode request: %s", err)
+				return
+			}
+
+			mock.lock.Lock()
+			mock.raw = append(mock.raw, data)
+			mock.requests = append(mock.requests, req)
+			mock.lock.Unlock()
+
+			response, err := internal.MarshalCBOR(map[string]any{"id": req.ID, "result": result})
+			if err != nil {
+				t.Errorf("mock server failed to encode response: %s", err)
+				return
+			}
+			if err := conn.WriteMessage(websocket.BinaryMessage, response); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(mock.server.Close)
+
+	return mock
+}
+
+func (mock *cborServer) config() *Config.DbConfig {
+	return &Config.DbConfig{
+		Url:      "ws" + strings.TrimPrefix(mock.server.URL, "http") + "/rpc",
+		Timeouts: &Config.DbTimeoutConfig{Timeout: 2 * time.Second},
+		Protocol: Config.ProtocolCBOR,
+	}
+}
+
+func TestCBOR_KeepsTypedValues(t *testing.T) {
+	created := time.Date(2023, 4, 5, 6, 7, 8, 9, time.UTC)
+	account := cborAccount{
+		ID:       surrealdb.NewRecordID("account", "bob smith"),
+		Owner:    surrealdb.NewRecordID("user", 42),
+		Created:  created,
+		Lifetime: surrealdb.Duration(36*time.Hour + 30*time.Minute),
+		Balance:  "12345678901234567890.123456789",
+		Name:     "savings",
+	}
+
+	mock := newCborServer(t, []string{"cbor"}, []map[string]any{{"status": "OK", "time": "1ms", "result": []cborAccount{account}}})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	result := surrealdb.QueryOn[cborAccount](db, "SELECT * FROM account WHERE owner = $owner", map[string]any{"owner": account.Owner, "at": created})
+	require.NoError(t, result.Error())
+
+	got := result.First()
+	require.NotNil(t, got)
+	require.Equal(t, account.ID, got.ID)
+	require.Equal(t, surrealdb.RecordID{Table: "user", ID: int64(42)}, got.Owner)
+	require.True(t, created.Equal(got.Created), got.Created)
+	require.Equal(t, account.Lifetime, got.Lifetime)
+	require.Equal(t, account.Balance, got.Balance)
+	require.Equal(t, "savings", got.Name)
+
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	require.Len(t, mock.requests, 1)
+	require.Equal(t, "query", mock.requests[0].Method)
+	require.JSONEq(t, `{"owner":"user:42","at":"2023-04-05T06:07:08.000000009Z"}`, string(mock.requests[0].Params[1]))
+
+	// the params are sent as a record id(tag 8) and a datetime(tag 12), rather than strings
+	require.True(t, bytes.Contains(mock.raw[0], []byte{0xc8, 0x82, 0x64, 'u', 's', 'e', 'r', 0x18, 42}), "record id tag not found")
+	require.True(t, bytes.Contains(mock.raw[0], []byte{0xcc, 0x82}), "datetime tag not found")
+}
+
+func TestCBOR_UntypedResultsAreJSONStrings(t *testing.T) {
+	record := map[string]any{"id": surrealdb.NewRecordID("account", "bob"), "lifetime": surrealdb.Duration(time.Hour)}
+	mock := newCborServer(t, []string{"cbor"}, []map[string]any{{"status": "OK", "time": "1ms", "result": []any{record}}})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	// only the typed values in the result types get their types back, see values.go
+	result := surrealdb.QueryOn[map[string]any](db, "SELECT * FROM account")
+	require.NoError(t, result.Error())
+	require.Equal(t, map[string]any{"id": "account:bob", "lifetime": "1h"}, *result.First())
+}
+
+func TestCBOR_ServerWithoutSupport(t *testing.T) {
+	mock := newCborServer(t, nil, nil)
+
+	_, err := surrealdb.New(mock.config())
+	require.Error(t, err)
+	require.True(t, errors.Is(err, surrealdb.ErrProtocolNotSupported), err.Error())
+}
+
+func TestCBOR_HttpIsNotSupported(t *testing.T) {
+	// rejected by the config validation, before the transport is created
+	_, err := surrealdb.New(&Config.DbConfig{Url: "http://localhost:8000", Protocol: Config.ProtocolCBOR})
+	require.Error(t, err)
+	require.True(t, errors.Is(err, Config.ErrInvalidConfig), err.Error())
+}
+
+func TestCBOR_RejectsHostileMessages(t *testing.T) {
+	for name, data := range map[string][]byte{
+		// arrays nested deeper than any response would be
+		"nested arrays": bytes.Repeat([]byte{0x81}, 100000),
+		// and the same with tags
+		"nested tags": bytes.Repeat([]byte{0xd8, 0x64}, 100000),
+		// an array declaring 2^64-1 items, with none of them sent
+		"array length": {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		// a map declaring 2^32-1 pairs, followed by a single byte
+		"map length": {0xba, 0xff, 0xff, 0xff, 0xff, 0x00},
+		// a string longer than the data
+		"string length": {0x7a, 0xff, 0xff, 0xff, 0xff, 'a'},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var dst bytes.Buffer
+			err := internal.CBORToJSON(&dst, data)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, internal.ErrInvalidCBOR), err.Error())
+		})
+	}
+}
+
+func FuzzCBORToJSON(f *testing.F) {
+	account, err := internal.MarshalCBOR(cborAccount{
+		ID:       surrealdb.NewRecordID("account", "bob smith"),
+		Owner:    surrealdb.NewRecordID("user", 42),
+		Created:  time.Date(2023, 4, 5, 6, 7, 8, 9, time.UTC),
+		Lifetime: surrealdb.Duration(36 * time.Hour),
+		Balance:  "12.5",
+		Name:     "savings",
+	})
+	require.NoError(f, err)
+
+	f.Add(account)
+	f.Add([]byte{0x9f, 0x01, 0x7f, 0x61, 'a', 0x61, 'b', 0xff, 0xf9, 0x7c, 0x00, 0xff})
+	f.Add([]byte{0xbf, 0x01, 0xd8, 0x25, 0x50, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 0xff})
+	f.Add([]byte{0xca, 0x64, '1', 'e', '9', '9'})
+	// a decimal which isn't a json number, because of its leading zeros
+	f.Add([]byte{0xca, 0x64, '0', '0', '0', '0'})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var dst bytes.Buffer
+		if err := internal.CBORToJSON(&dst, data); err != nil {
+			return
+		}
+		if !json.Valid(dst.Bytes()) {
+			t.Fatalf("%x was transcoded into invalid json: %s", data, dst.Bytes())
+		}
+	})
+}
+
+func FuzzCBORRoundTrip(f *testing.F) {
+	type value struct {
+		Text    string    `json:"text"`
+		Integer int64     `json:"integer"`
+		Float   float64   `json:"float"`
+		Bytes   []byte    `json:"bytes"`
+		Items   []string  `json:"items"`
+		Nested  *value    `json:"nested"`
+		Time    time.Time `json:"time"`
+	}
+
+	f.Add("bob", int64(-1), 1.5, []byte{1, 2, 3}, int64(1680674828))
+	f.Add("", int64(math.MinInt64), -0.0, []byte(nil), int64(0))
+	f.Add("⟨user⟩:\"quoted\"\n", int64(math.MaxInt64), 1e300, []byte{}, int64(-62135596800))
+
+	f.Fuzz(func(t *testing.T, text string, integer int64, float float64, data []byte, seconds int64) {
+		// invalid utf-8 is replaced when it's transcoded, and json has no NaN or infinity
+		if !utf8.ValidString(text) || math.IsNaN(float) || math.IsInf(float, 0) {
+			t.Skip()
+		}
+		// only the years RFC 3339 can hold
+		at := time.Unix(seconds, 0).UTC()
+		if at.Year() < 0 || at.Year() > 9999 {
+			t.Skip()
+		}
+
+		original := value{
+			Text:    text,
+			Integer: integer,
+			Float:   float,
+			Bytes:   data,
+			Items:   []string{text, ""},
+			Nested:  &value{Text: text, Items: []string{}, Time: at},
+			Time:    at,
+		}
+
+		encoded, err := internal.MarshalCBOR(original)
+		require.NoError(t, err)
+
+		var transcoded bytes.Buffer
+		require.NoError(t, internal.CBORToJSON(&transcoded, encoded))
+
+		var decoded value
+		require.NoError(t, json.Unmarshal(transcoded.Bytes(), &decoded), transcoded.String())
+
+		// nil and empty byte slices are both null
+		if len(original.Bytes) == 0 {
+			original.Bytes, decoded.Bytes = nil, nil
+		}
+		require.Equal(t, original, decoded)
+	})
+}
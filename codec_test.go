@@ -0,0 +1,194 @@
+package surrealdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+// recordingCodec wraps a codec, remembering everything it encoded
+type recordingCodec struct {
+	Config.Codec
+
+	lock    sync.Mutex
+	encoded []string
+}
+
+func (codec *recordingCodec) Marshal(v any) ([]byte, error) {
+	data, err := codec.Codec.Marshal(v)
+
+	codec.lock.Lock()
+	codec.encoded = append(codec.encoded, string(data))
+	codec.lock.Unlock()
+
+	return data, err
+}
+
+func TestCodec_UsedForRequestsAndResults(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob"}}}}, ""
+	})
+
+	codec := &recordingCodec{Codec: Config.StdCodec{}}
+
+	config := mock.config()
+	config.Codec = codec
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	user := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user WHERE ->likes->post", map[string]any{"name": "bob"}).First()
+	require.NotNil(t, user)
+	require.Equal(t, "bob", user.Username)
+
+	codec.lock.Lock()
+	defer codec.lock.Unlock()
+
+	require.Len(t, codec.encoded, 1)
+	require.True(t, strings.Contains(codec.encoded[0], `"params":["SELECT * FROM user WHERE ->likes->post",{"name":"bob"}]`), codec.encoded[0])
+}
+
+func TestCodec_StrictDecoding(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob", "unknown": true}}}}, ""
+	})
+
+	config := mock.config()
+	config.Codec = Config.StdCodec{DisallowUnknownFields: true}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	result := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user")
+	require.Error(t, result.Error())
+	require.True(t, strings.Contains(result.Error().Error(), "unknown"), result.Error().Error())
+}
+
+type metaUser struct {
+	Username string          `json:"username"`
+	Meta     json.RawMessage `json:"meta"`
+}
+
+func TestCodec_ResultsOutliveTheResponseBuffer(t *testing.T) {
+	for name, codec := range map[string]Config.Codec{"goccy": Config.GoccyCodec{}, "std": Config.StdCodec{}} {
+		t.Run(name, func(t *testing.T) {
+			var lock sync.Mutex
+			n := 0
+			mock := newMockServer(t, func(req mockRequest) (any, string) {
+				lock.Lock()
+				defer lock.Unlock()
+				n++
+
+				user := map[string]any{"username": fmt.Sprintf("user-%d", n), "meta": map[string]any{"n": n}}
+				return []map[string]any{{"status": "OK", "time": "1ms", "result": []any{user}}}, ""
+			})
+
+			config := mock.config()
+			config.Codec = codec
+
+			db, err := surrealdb.New(config)
+			require.NoError(t, err)
+			defer db.Close()
+
+			first := surrealdb.QueryOn[metaUser](db, "SELECT * FROM user").First()
+			require.NotNil(t, first)
+
+			// the buffer the first response was read into is reused by these
+			for i := 0; i < 20; i++ {
+				require.NotNil(t, surrealdb.QueryOn[metaUser](db, "SELECT * FROM user").First())
+			}
+
+			require.Equal(t, "user-1", first.Username)
+			require.JSONEq(t, `{"n":1}`, string(first.Meta))
+		})
+	}
+}
+
+// aliasingCodec keeps the data it decodes a statement from, like a zero-copy codec would
+type aliasingCodec struct {
+	Config.GoccyCodec
+}
+
+func (codec aliasingCodec) Unmarshal(data []byte, v any) error {
+	if err := codec.GoccyCodec.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if statement, ok := v.(*surrealdb.ResultQuery[metaUser]); ok && len(statement.Result) > 0 {
+		statement.Result[0].Meta = data
+	}
+
+	return nil
+}
+
+func TestCodec_ResponsesAreKeptWhenTheyCanStillBeUsed(t *testing.T) {
+	newDB := func(t *testing.T, codec Config.Codec) *surrealdb.DB {
+		var lock sync.Mutex
+		n := 0
+		mock := newMockServer(t, func(req mockRequest) (any, string) {
+			lock.Lock()
+			defer lock.Unlock()
+			n++
+
+			user := map[string]any{"username": fmt.Sprintf("user-%d", n), "meta": map[string]any{"n": n}}
+			return []map[string]any{{"status": "OK", "time": "1ms", "result": []any{user}}}, ""
+		})
+
+		config := mock.config()
+		config.Codec = codec
+
+		db, err := surrealdb.New(config)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		return db
+	}
+
+	t.Run("custom codec", func(t *testing.T) {
+		db := newDB(t, aliasingCodec{})
+
+		first := surrealdb.QueryOn[metaUser](db, "SELECT * FROM user").First()
+		require.NotNil(t, first)
+		meta := string(first.Meta)
+		require.True(t, strings.Contains(meta, "user-1"), meta)
+
+		for i := 0; i < 20; i++ {
+			require.NotNil(t, surrealdb.QueryOn[metaUser](db, "SELECT * FROM user").First())
+		}
+
+		require.Equal(t, meta, string(first.Meta))
+	})
+
+	t.Run("interceptor", func(t *testing.T) {
+		db := newDB(t, nil)
+
+		var kept []*surrealdb.RawResponse
+		db.Intercept(func(next surrealdb.Invoker) surrealdb.Invoker {
+			return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+				result, err := next(ctx, method, params)
+				if err == nil {
+					kept = append(kept, result)
+				}
+				return result, err
+			}
+		})
+
+		require.NotNil(t, surrealdb.QueryOn[metaUser](db, "SELECT * FROM user").First())
+		first := string(kept[0].Result().Result)
+		require.True(t, strings.Contains(first, "user-1"), first)
+
+		for i := 0; i < 20; i++ {
+			require.NotNil(t, surrealdb.QueryOn[metaUser](db, "SELECT * FROM user").First())
+		}
+
+		require.Equal(t, first, string(kept[0].Result().Result))
+	})
+}
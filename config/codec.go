@@ -0,0 +1,99 @@
+package Config
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// Codec encodes the requests we send(including their params), and decodes the responses and their results
+// Wrap one of the built-in codecs to handle your own types, or implement it to use another library
+//
+// Responses are read into pooled buffers, they're only reused with the built-in codecs, which copy what they decode
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// StreamCodec is implemented by codecs which can encode straight into a writer,
+// so requests are encoded into a pooled buffer instead of a new slice every time
+type StreamCodec interface {
+	Codec
+	Encode(w io.Writer, v any) error
+}
+
+// GoccyCodec uses github.com/goccy/go-json, this is the default
+type GoccyCodec struct{}
+
+func (GoccyCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := (GoccyCodec{}).Encode(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (GoccyCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (GoccyCodec) Encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	// the default HTML escaping messes with select arrows
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(v)
+}
+
+// StdCodec uses encoding/json from the standard library
+type StdCodec struct {
+	// Results with fields that don't exist on the type they're decoded into fail to decode
+	DisallowUnknownFields bool
+	// Numbers decoded into an interface are a json.Number instead of a float64, so large integers keep their precision
+	UseNumber bool
+}
+
+func (codec StdCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (codec StdCodec) Unmarshal(data []byte, v any) error {
+	if !codec.DisallowUnknownFields && !codec.UseNumber {
+		return stdjson.Unmarshal(data, v)
+	}
+
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	if codec.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if codec.UseNumber {
+		dec.UseNumber()
+	}
+
+	return dec.Decode(v)
+}
+
+func (codec StdCodec) Encode(w io.Writer, v any) error {
+	enc := stdjson.NewEncoder(w)
+	// the default HTML escaping messes with select arrows
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(v)
+}
+
+// CodecOrDefault returns the configured Codec, or GoccyCodec when it isn't set
+func (c *DbConfig) CodecOrDefault() Codec {
+	if c.Codec == nil {
+		return GoccyCodec{}
+	}
+
+	return c.Codec
+}
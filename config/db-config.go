@@ -1,12 +1,134 @@
 package Config
 
-import "time"
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Protocol is the format messages are sent in over the websocket
+type Protocol string
+
+const (
+	// ProtocolJSON sends text messages containing json, this is the default
+	ProtocolJSON Protocol = "json"
+	// ProtocolCBOR sends binary messages containing cbor, so datetimes, durations, decimals and record ids in the params
+	// are sent as their SurrealDB types rather than strings. Responses are transcoded to json, where those values are the
+	// same strings the json protocol returns
+	// It's negotiated as the "cbor" websocket subprotocol, which needs a version of SurrealDB that supports it
+	ProtocolCBOR Protocol = "cbor"
+)
 
 type DbTimeoutConfig struct {
 	// Time in seconds to wait
 	Timeout time.Duration
 }
 
+type DbReconnectConfig struct {
+	// The maximum amount of attempts before giving up, 0 will retry forever
+	MaxRetries int
+	// The delay before the first attempt, this is multiplied after every failed attempt
+	InitialDelay time.Duration
+	// The upper limit of the delay between attempts
+	MaxDelay time.Duration
+	// The factor the delay is multiplied by after every failed attempt, defaults to 2
+	Multiplier float64
+}
+
+// Delay returns the backoff delay for the given attempt(starting at 0)
+func (c *DbReconnectConfig) Delay(attempt int) time.Duration {
+	delay := c.InitialDelay
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+
+	multiplier := c.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	for i := 0; i < attempt; i++ {
+		delay = time.Duration(float64(delay) * multiplier)
+		if c.MaxDelay > 0 && delay >= c.MaxDelay {
+			return c.MaxDelay
+		}
+	}
+
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		return c.MaxDelay
+	}
+
+	return delay
+}
+
+type DbPoolConfig struct {
+	// The amount of connections which are always kept open, defaults to 1
+	MinConnections int
+	// The upper limit of open connections, defaults to MinConnections
+	MaxConnections int
+	// How often every connection is pinged, unhealthy connections are closed and replaced
+	// Set this to 0 to disable health checks
+	HealthCheckInterval time.Duration
+	// Connections above MinConnections which haven't been used for this long are closed
+	// Set this to 0 to keep them open
+	IdleTimeout time.Duration
+}
+
+// DefaultKeepaliveInterval is used when DbKeepaliveConfig.Interval is 0
+const DefaultKeepaliveInterval = 30 * time.Second
+
+type DbKeepaliveConfig struct {
+	// How often a ping is sent, defaults to DefaultKeepaliveInterval
+	Interval time.Duration
+	// How long we wait for the pong(or any other message) after a ping, defaults to Interval
+	// When nothing arrives in time, the connection is treated as lost
+	Timeout time.Duration
+}
+
+// PingInterval returns how often a ping is sent
+func (c *DbKeepaliveConfig) PingInterval() time.Duration {
+	if c.Interval <= 0 {
+		return DefaultKeepaliveInterval
+	}
+
+	return c.Interval
+}
+
+// Deadline returns how long the connection may stay silent before it's considered dead
+func (c *DbKeepaliveConfig) Deadline() time.Duration {
+	interval := c.PingInterval()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = interval
+	}
+
+	return interval + timeout
+}
+
+type DbSendQueueConfig struct {
+	// The amount of requests which can wait to be written, defaults to 256
+	Size int
+	// When the queue is full, requests fail straight away with ErrQueueFull
+	// By default they wait for space, until their context is done
+	FailFast bool
+}
+
+type DbDialConfig struct {
+	// Headers are sent with the websocket handshake(and every request when using http),
+	// for example the NS/DB or auth headers your gateway expects
+	Headers http.Header
+	// How long the websocket handshake may take, defaults to 45 seconds
+	HandshakeTimeout time.Duration
+	// The maximum size of a single message we accept, 0 means no limit
+	ReadLimit int64
+	// Compression is negotiated by default, set this to disable it
+	DisableCompression bool
+	// Proxy returns the proxy to use for a request, defaults to http.ProxyFromEnvironment
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
 type DbConfig struct {
 	Url       string
 	Username  string
@@ -20,4 +142,30 @@ type DbConfig struct {
 	// This will configure context automatically and setup timeouts
 	// Set this to nil to disable auto timeout configuration via ctx
 	Timeouts *DbTimeoutConfig
+	// When the connection drops, we will try to re-establish it using exponential backoff
+	// Once connected again, the session state(signin/authenticate, use and let variables) is replayed
+	// Set this to nil to disable reconnecting
+	Reconnect *DbReconnectConfig
+	// TLS is used for wss:// and https:// urls, when nil the system defaults are used
+	// Set Certificates for client certificates, and RootCAs for a custom CA
+	TLS *tls.Config
+	// Dial configures how the connection is established, when nil the defaults are used
+	Dial *DbDialConfig
+	// SendQueue configures the queue of requests waiting to be written to the websocket, when nil the defaults are used
+	SendQueue *DbSendQueueConfig
+	// Protocol is the format used over the websocket, defaults to ProtocolJSON
+	// The http transport only supports json
+	Protocol Protocol
+	// Codec encodes requests and decodes responses and results, when nil GoccyCodec is used
+	// With ProtocolCBOR, requests are encoded as cbor and responses are transcoded to json before the codec decodes them
+	Codec Codec
+	// Keepalive sends websocket pings, so a half-open connection is noticed and handled like any other disconnect
+	// Set this to nil to disable pings, the connection is then only considered lost when reading or writing fails
+	Keepalive *DbKeepaliveConfig
+	// Logger receives connection, auth and request events, when nil nothing is logged
+	// See StdLogger and SlogLogger
+	Logger Logger
+	// Observer is told about every request and connection state change, for metrics and tracing
+	// Combine several through MultiObserver
+	Observer Observer
 }
@@ -0,0 +1,95 @@
+package Config
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel is how important a log event is, the values are the same as log/slog's levels
+type LogLevel int
+
+const (
+	// LogDebug is used for every request, including their params(with credentials redacted)
+	LogDebug LogLevel = -4
+	// LogInfo is used for connecting, disconnecting and authenticating
+	LogInfo LogLevel = 0
+	// LogWarn is used for things we recover from, like a lost connection, a failed reconnect attempt or a failed request
+	LogWarn LogLevel = 4
+	// LogError is used for things we can't recover from, like a response we couldn't decode or a request the connection failed
+	LogError LogLevel = 8
+)
+
+func (level LogLevel) String() string {
+	switch {
+	case level < LogInfo:
+		return "DEBUG"
+	case level < LogWarn:
+		return "INFO"
+	case level < LogError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger receives the events of a connection, attrs are alternating keys and values like log/slog
+type Logger interface {
+	// Enabled reports whether events at level are logged, so we don't build events nobody will see
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, msg string, attrs ...any)
+}
+
+// NopLogger discards every event, this is the default
+type NopLogger struct{}
+
+func (NopLogger) Enabled(LogLevel) bool { return false }
+
+func (NopLogger) Log(LogLevel, string, ...any) {}
+
+// StdLogger writes events at or above Level to a logger from the standard log package,
+// for example: SurrealDB: WARN connection lost error="EOF"
+type StdLogger struct {
+	// Logger defaults to the standard logger
+	Logger *log.Logger
+	Level  LogLevel
+}
+
+func (l StdLogger) Enabled(level LogLevel) bool {
+	return level >= l.Level
+}
+
+func (l StdLogger) Log(level LogLevel, msg string, attrs ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("SurrealDB: ")
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+
+	for i := 0; i < len(attrs); i += 2 {
+		if i+1 == len(attrs) {
+			fmt.Fprintf(&b, " !BADKEY=%v", attrs[i])
+			break
+		}
+		fmt.Fprintf(&b, " %v=%q", attrs[i], fmt.Sprint(attrs[i+1]))
+	}
+
+	if l.Logger == nil {
+		log.Println(b.String())
+		return
+	}
+	l.Logger.Println(b.String())
+}
+
+// LoggerOrDefault returns the configured Logger, or NopLogger when it isn't set
+func (c *DbConfig) LoggerOrDefault() Logger {
+	if c.Logger == nil {
+		return NopLogger{}
+	}
+
+	return c.Logger
+}
@@ -0,0 +1,35 @@
+//go:build go1.21
+
+package Config
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger sends events to a log/slog logger, at the slog level with the same value
+type SlogLogger struct {
+	// Logger defaults to slog.Default()
+	Logger *slog.Logger
+}
+
+// NewSlogLogger creates a Logger which sends events to logger
+func NewSlogLogger(logger *slog.Logger) SlogLogger {
+	return SlogLogger{Logger: logger}
+}
+
+func (l SlogLogger) Enabled(level LogLevel) bool {
+	return l.logger().Enabled(context.Background(), slog.Level(level))
+}
+
+func (l SlogLogger) Log(level LogLevel, msg string, attrs ...any) {
+	l.logger().Log(context.Background(), slog.Level(level), msg, attrs...)
+}
+
+func (l SlogLogger) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+
+	return l.Logger
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+
+package Config_test
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var out strings.Builder
+
+	handler := slog.NewTextHandler(&out, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+
+	logger := Config.NewSlogLogger(slog.New(handler))
+	require.False(t, logger.Enabled(Config.LogDebug))
+	require.True(t, logger.Enabled(Config.LogWarn))
+
+	logger.Log(Config.LogWarn, "connection lost", "error", "EOF")
+
+	require.Equal(t, "level=WARN msg=\"connection lost\" error=EOF\n", out.String())
+}
@@ -0,0 +1,22 @@
+package Config_test
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+func TestStdLogger(t *testing.T) {
+	var out strings.Builder
+
+	logger := Config.StdLogger{Logger: log.New(&out, "", 0), Level: Config.LogInfo}
+	require.False(t, logger.Enabled(Config.LogDebug))
+
+	logger.Log(Config.LogDebug, "request started")
+	logger.Log(Config.LogWarn, "connection lost", "error", "EOF", "reconnect", true)
+
+	require.Equal(t, "SurrealDB: WARN connection lost error=\"EOF\" reconnect=\"true\"\n", out.String())
+}
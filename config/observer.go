@@ -0,0 +1,95 @@
+package Config
+
+import (
+	"context"
+	"time"
+)
+
+// ConnectionState is the state of the connection to the database
+type ConnectionState int
+
+const (
+	// ConnectionConnecting is the state until the first connection is established
+	ConnectionConnecting ConnectionState = iota
+	ConnectionConnected
+	// ConnectionReconnecting is the state after the connection was lost, whilst we try to re-establish it
+	ConnectionReconnecting
+	// ConnectionClosed is the final state, after Close or when reconnecting gave up
+	ConnectionClosed
+)
+
+func (state ConnectionState) String() string {
+	switch state {
+	case ConnectionConnecting:
+		return "connecting"
+	case ConnectionConnected:
+		return "connected"
+	case ConnectionReconnecting:
+		return "reconnecting"
+	case ConnectionClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// RequestStart describes a request which is about to be sent
+type RequestStart struct {
+	Method string
+	ID     string
+	// ParamSize is the size of the params encoded by the codec, in bytes
+	ParamSize int
+}
+
+// RequestEnd describes a request which has completed, Err is set when it failed
+type RequestEnd struct {
+	RequestStart
+	Duration time.Duration
+	Err      error
+}
+
+// Observer is told about every request and every change of the connection state, use it for metrics and tracing
+// Callbacks are made from the goroutine sending the request, or the one handling the connection, so they should be quick
+type Observer interface {
+	// OnRequestStart is called before the request is sent, the returned context is used for the
+	// request and passed to OnRequestEnd, so it can carry a span for example
+	OnRequestStart(ctx context.Context, req RequestStart) context.Context
+	OnRequestEnd(ctx context.Context, req RequestEnd)
+	OnConnectionState(from ConnectionState, to ConnectionState)
+}
+
+// NopObserver ignores everything, embed it to only implement the callbacks you need
+type NopObserver struct{}
+
+func (NopObserver) OnRequestStart(ctx context.Context, _ RequestStart) context.Context { return ctx }
+
+func (NopObserver) OnRequestEnd(context.Context, RequestEnd) {}
+
+func (NopObserver) OnConnectionState(ConnectionState, ConnectionState) {}
+
+// MultiObserver tells every one of the observers, in order
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (observers multiObserver) OnRequestStart(ctx context.Context, req RequestStart) context.Context {
+	for _, observer := range observers {
+		ctx = observer.OnRequestStart(ctx, req)
+	}
+
+	return ctx
+}
+
+func (observers multiObserver) OnRequestEnd(ctx context.Context, req RequestEnd) {
+	for _, observer := range observers {
+		observer.OnRequestEnd(ctx, req)
+	}
+}
+
+func (observers multiObserver) OnConnectionState(from ConnectionState, to ConnectionState) {
+	for _, observer := range observers {
+		observer.OnConnectionState(from, to)
+	}
+}
@@ -1,8 +1,9 @@
 package surrealdb
 
 import (
+	"context"
 	"errors"
-	"log"
+	"sync"
 
 	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
 	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
@@ -10,57 +11,96 @@ import (
 
 var (
 	ErrInvalidLoginResponse = errors.New("invalid login response")
+	// ErrConnectionClosed is returned for in-flight requests when the connection drops, or DB.Close is called
+	ErrConnectionClosed = internal.ErrConnectionClosed
+	// ErrQueueFull is returned when the send queue is full, and Config.DbSendQueueConfig.FailFast is set
+	ErrQueueFull = internal.ErrQueueFull
+	// ErrProtocolNotSupported is returned when the server doesn't support Config.ProtocolCBOR
+	ErrProtocolNotSupported = internal.ErrProtocolNotSupported
 )
 
-// DB is a client for the SurrealDB database that holds are websocket connection.
+// DB is a client for the SurrealDB database that holds are connection(websocket by default).
 type DB struct {
-	ws *internal.WS
+	transport Transport
+	// codec decodes results, it's the same codec the transport uses
+	codec    Config.Codec
+	logger   Config.Logger
+	observer Config.Observer // nil unless one is configured
+
+	// interceptors wrap every request, see Intercept
+	interceptors struct {
+		lock    sync.RWMutex
+		chain   []Interceptor
+		invoker Invoker
+	}
+
+	// live holds the live queries started through Live, by the id we returned
+	live struct {
+		lock    sync.Mutex
+		streams map[string]*internal.LiveStream
+	}
+
+	// tokens holds the token manager, once it's been enabled through EnableTokenRefresh
+	// credentials and token are from the last signin/signup/authenticate, so a manager enabled
+	// after signing in(like with AutoLogin) can still use them
+	tokens struct {
+		lock        sync.Mutex
+		manager     *tokenManager
+		credentials any
+		token       string
+	}
 }
 
-var dbConfig *Config.DbConfig
+// Connection is the default connection, it's the first DB created by New unless another is registered
+// as the DefaultConnection, see Register
 var Connection *DB
 
 // New Creates a new DB instance given a WebSocket URL.
+// When given a http(s) URL, the http transport is used instead.
+// Every call opens a new connection, the first one also becomes the DefaultConnection
+// The config is validated first, an invalid one returns an error matching Config.ErrInvalidConfig
 func New(config *Config.DbConfig) (*DB, error) {
-	saveGlobalConf := true
-	if Connection != nil {
-		if dbConfig.Url == config.Url &&
-			dbConfig.Username == config.Username &&
-			dbConfig.Password == config.Password &&
-			dbConfig.Database == config.Database &&
-			dbConfig.Namespace == config.Namespace {
-			log.Println("SurrealDB: DB already initialized, configurations match the current configuration, reusing the existing connection")
-			return Connection, nil
-		} else {
-			log.Println("SurrealDB: DB already initialized, this new initialization will not override the existing, so make sure you save a reference.")
-			saveGlobalConf = false
-		}
-	}
+	return newDB(config, nil, nil)
+}
+
+// NewWithTransport Creates a new DB instance which uses the given transport to talk to the database
+func NewWithTransport(config *Config.DbConfig, transport Transport) (*DB, error) {
+	return newDB(config, transport, nil)
+}
 
-	conf := &Config.DbConfig{
-		Url:       config.Url,
-		Username:  config.Username,
-		Password:  config.Password,
-		Database:  config.Database,
-		Namespace: config.Namespace,
-		AutoLogin: config.AutoLogin,
-		AutoUse:   config.AutoUse,
-		Timeouts:  config.Timeouts,
+// NewWithInterceptors is the same as New, but the interceptors are already in place for the requests made by
+// AutoLogin and AutoUse, see DB.Intercept
+func NewWithInterceptors(config *Config.DbConfig, interceptors ...Interceptor) (*DB, error) {
+	return newDB(config, nil, interceptors)
+}
+
+func newDB(config *Config.DbConfig, transport Transport, interceptors []Interceptor) (*DB, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
-	if saveGlobalConf {
-		dbConfig = conf
+
+	confCopy := *config
+	conf := &confCopy
+
+	if transport == nil {
+		var err error
+		transport, err = newTransport(conf)
+		if err != nil {
+			return nil, internal.ClassifyError(err)
+		}
 	}
 
-	ws, err := internal.NewWebsocket(conf.Url, config.Timeouts)
-	if err != nil {
-		return nil, err
+	inst := &DB{transport: transport, codec: conf.CodecOrDefault(), logger: conf.LoggerOrDefault(), observer: conf.Observer}
+	if len(interceptors) > 0 {
+		inst.Intercept(interceptors...)
 	}
 
-	inst := &DB{ws}
+	var err error
 
 	if conf.AutoLogin {
 		_, err = inst.Signin(UserInfo{User: conf.Username, Password: conf.Password})
 		if err != nil {
+			inst.Close()
 			return nil, err
 		}
 	}
@@ -68,13 +108,12 @@ func New(config *Config.DbConfig) (*DB, error) {
 	if conf.AutoUse {
 		_, err = inst.Use(conf.Namespace, conf.Database)
 		if err != nil {
+			inst.Close()
 			return nil, err
 		}
 	}
 
-	if saveGlobalConf {
-		Connection = inst
-	}
+	registerDefault(inst)
 
 	return inst, nil
 }
@@ -83,31 +122,51 @@ func New(config *Config.DbConfig) (*DB, error) {
 // Public methods
 // --------------------------------------------------
 
-// Close closes the underlying WebSocket connection.
+// Close closes the underlying connection, and removes it from the connection registry
 func (db *DB) Close() error {
-	return db.ws.Close()
+	db.DisableTokenRefresh()
+	unregisterAll(db)
+
+	return db.transport.Close()
 }
 
 // --------------------------------------------------
 
 // Use is a method to select the namespace and table to use.
 func (db *DB) Use(ns string, dbname string) (any, error) {
-	return db.send("use", ns, dbname)
+	return db.UseCtx(context.Background(), ns, dbname)
+}
+
+// UseCtx is the same as Use, but honours the cancellation/deadline of ctx
+func (db *DB) UseCtx(ctx context.Context, ns string, dbname string) (any, error) {
+	return db.send(ctx, "use", ns, dbname)
 }
 
 func (db *DB) Info() (any, error) {
-	return db.send("info")
+	return db.InfoCtx(context.Background())
+}
+
+func (db *DB) InfoCtx(ctx context.Context) (any, error) {
+	return db.send(ctx, "info")
 }
 
 // Signup is a helper method for signing up a new user.
 func (db *DB) Signup(vars any) (any, error) {
-	return db.send("signup", vars)
+	return db.SignupCtx(context.Background(), vars)
+}
+
+func (db *DB) SignupCtx(ctx context.Context, vars any) (any, error) {
+	return db.send(ctx, "signup", vars)
 }
 
 // SignupUser is a helper method for signing in a user and returning a typed response
 func (db *DB) SignupUser(vars UserInfo) (*AuthenticationResult, error) {
+	return db.SignupUserCtx(context.Background(), vars)
+}
+
+func (db *DB) SignupUserCtx(ctx context.Context, vars UserInfo) (*AuthenticationResult, error) {
 	authResult := &AuthenticationResult{Success: false}
-	result, err := db.send("signup", vars)
+	result, err := db.send(ctx, "signup", vars)
 	if err != nil {
 		return authResult, err
 	}
@@ -119,18 +178,23 @@ func (db *DB) SignupUser(vars UserInfo) (*AuthenticationResult, error) {
 
 // Signin is a helper method for signing in a user.
 func (db *DB) Signin(vars UserInfo) (any, error) {
-	return db.send("signin", vars)
+	return db.SigninCtx(context.Background(), vars)
+}
+
+func (db *DB) SigninCtx(ctx context.Context, vars UserInfo) (any, error) {
+	return db.send(ctx, "signin", vars)
 }
 
 // SigninUser is a helper method for signing in a user and returning a typed response
-// Note: This will probably fail when signing in as a root user, but for
+// Note: This will probably fail when signing in as a root user(see SigninRoot), but for
 // a regular user(via a scope for example) we get a JWT response
 func (db *DB) SigninUser(vars UserInfo) (*AuthenticationResult, error) {
+	return db.SigninUserCtx(context.Background(), vars)
+}
+
+func (db *DB) SigninUserCtx(ctx context.Context, vars UserInfo) (*AuthenticationResult, error) {
 	authResult := &AuthenticationResult{Success: false}
-	result, err := db.send("signin", vars)
-	if err != nil {
-		return authResult, err
-	}
+	result, err := db.send(ctx, "signin", vars)
 	if err != nil {
 		return authResult, err
 	}
@@ -141,58 +205,228 @@ func (db *DB) SigninUser(vars UserInfo) (*AuthenticationResult, error) {
 }
 
 func (db *DB) Invalidate() (any, error) {
-	return db.send("invalidate")
+	return db.InvalidateCtx(context.Background())
+}
+
+func (db *DB) InvalidateCtx(ctx context.Context) (any, error) {
+	return db.send(ctx, "invalidate")
 }
 
 func (db *DB) Authenticate(token string) (any, error) {
-	return db.send("authenticate", token)
+	return db.AuthenticateCtx(context.Background(), token)
+}
+
+func (db *DB) AuthenticateCtx(ctx context.Context, token string) (any, error) {
+	return db.send(ctx, "authenticate", token)
 }
 
 // --------------------------------------------------
 
 func (db *DB) Live(table string) (any, error) {
-	return db.send("live", table)
+	return db.LiveCtx(context.Background(), table)
+}
+
+// LiveCtx starts a live query on the table, returning its id
+// Over the websocket, the live query is restarted after reconnecting. The returned id keeps
+// working with LiveNotifications and Kill, even though the id on the database has changed
+func (db *DB) LiveCtx(ctx context.Context, table string) (any, error) {
+	transport, ok := db.transport.(liveTransport)
+	if !ok {
+		return db.send(ctx, "live", table)
+	}
+
+	stream, err := db.startLive(ctx, transport, "live", []any{table})
+	if err != nil {
+		return nil, err
+	}
+
+	liveId := stream.Id()
+
+	db.live.lock.Lock()
+	if db.live.streams == nil {
+		db.live.streams = make(map[string]*internal.LiveStream)
+	}
+	db.live.streams[liveId] = stream
+	db.live.lock.Unlock()
+
+	stream.OnClose(func() {
+		db.live.lock.Lock()
+		delete(db.live.streams, liveId)
+		db.live.lock.Unlock()
+	})
+
+	return liveId, nil
+}
+
+// LiveNotifications receives the raw notifications of a live query started through Live
+// Notifications are buffered until they're read, the channel is closed once the live query is killed
+func (db *DB) LiveNotifications(liveId string) (<-chan *RawResponse, error) {
+	stream := db.liveStream(liveId)
+	if stream == nil {
+		return nil, ErrUnknownLiveQuery
+	}
+
+	return stream.Notifications(), nil
 }
 
 func (db *DB) Kill(query string) (any, error) {
-	return db.send("kill", query)
+	return db.KillCtx(context.Background(), query)
+}
+
+func (db *DB) KillCtx(ctx context.Context, query string) (any, error) {
+	// the id on the database may have changed since it was started, so we let the stream kill it
+	if stream := db.liveStream(query); stream != nil {
+		return db.killStream(ctx, stream)
+	}
+
+	return db.send(ctx, "kill", query)
+}
+
+// startLive starts a live query through the interceptors, they receive the response to the request which started it
+// When an interceptor fails the request after it was started, the live query is killed again
+func (db *DB) startLive(ctx context.Context, transport liveTransport, method string, params []any) (*internal.LiveStream, error) {
+	var stream *internal.LiveStream
+
+	_, err := db.intercepted(func(ctx context.Context, method string, params []any) (*RawResponse, error) {
+		return db.roundTrip(ctx, method, params, func(ctx context.Context, id string) (*RawResponse, error) {
+			var err error
+			if stream, err = transport.Live(ctx, id, method, params); err != nil {
+				return nil, err
+			}
+
+			return stream.Response(), nil
+		})
+	})(ctx, method, params)
+	if err != nil {
+		if stream != nil {
+			db.killStream(context.Background(), stream)
+		}
+		return nil, err
+	}
+
+	return stream, nil
+}
+
+// roundTrip logs and observes a request, which is sent by calling send with its id
+// When it fails because the token expired, we authenticate again and send it once more with a new id
+func (db *DB) roundTrip(ctx context.Context, method string, params []any, send func(ctx context.Context, id string) (*internal.RPCRawResponse, error)) (*internal.RPCRawResponse, error) {
+	tokens := db.tokenManager()
+
+	var generation uint64
+	if tokens != nil {
+		generation = tokens.current()
+	}
+
+	id := xid()
+	started := db.logRequest(id, method, params)
+	ctx, observed := db.observeStart(ctx, id, method, params)
+
+	result, err := send(ctx, id)
+	err = internal.ClassifyError(err)
+
+	if err != nil && tokens != nil && !authMethods[method] && isAuthError(err) {
+		if retry, _ := tokens.refresh(ctx, generation); retry {
+			result, err = send(ctx, xid())
+			err = internal.ClassifyError(err)
+		}
+	}
+
+	db.logResponse(id, method, started, err)
+	db.observeEnd(ctx, observed, started, err)
+
+	return result, err
+}
+
+// killStream kills the live query of the stream through the interceptors, the stream is closed even when it fails
+// The kill is sent over the connection the live query was started on, with its current id
+func (db *DB) killStream(ctx context.Context, stream *internal.LiveStream) (*internal.RPCRawResponse, error) {
+	defer stream.Close()
+
+	return db.intercepted(func(ctx context.Context, method string, params []any) (*RawResponse, error) {
+		return db.roundTrip(ctx, method, params, func(ctx context.Context, id string) (*RawResponse, error) {
+			return stream.Send(ctx, id, method, params)
+		})
+	})(ctx, "kill", []any{stream.Id()})
+}
+
+func (db *DB) liveStream(liveId string) *internal.LiveStream {
+	db.live.lock.Lock()
+	defer db.live.lock.Unlock()
+
+	return db.live.streams[liveId]
 }
 
 func (db *DB) Let(key string, val any) (any, error) {
-	return db.send("let", key, val)
+	return db.LetCtx(context.Background(), key, val)
+}
+
+func (db *DB) LetCtx(ctx context.Context, key string, val any) (any, error) {
+	return db.send(ctx, "let", key, val)
 }
 
 // Query is a convenient method for sending a query to the database.
 func (db *DB) Query(sql string, vars any) (any, error) {
-	return db.send("query", sql, vars)
+	return db.QueryCtx(context.Background(), sql, vars)
+}
+
+func (db *DB) QueryCtx(ctx context.Context, sql string, vars any) (any, error) {
+	return db.send(ctx, "query", sql, vars)
+}
+
+// SendCtx sends any rpc method to the database, waiting for the response until ctx is done
+// The configured timeout still applies, whichever is reached first will cancel the request
+func (db *DB) SendCtx(ctx context.Context, method string, params ...any) (any, error) {
+	return db.send(ctx, method, params...)
 }
 
 // --------------------------------------------------
 // Private methods
 // --------------------------------------------------
 
-// send is a helper method for sending a query to the database.
-func (db *DB) send(method string, params ...any) (*internal.RPCRawResponse, error) {
-	id := xid()
+// send is a helper method for sending a query to the database, through the interceptors
+func (db *DB) send(ctx context.Context, method string, params ...any) (*internal.RPCRawResponse, error) {
+	return db.invoker()(ctx, method, params)
+}
 
-	// response, err := db.ws.Send(id, method, params)
+// release hands the buffer of a response back to the pool, once its result has been decoded
+// It's only reused when nothing else can still be using it: the built-in codecs copy what they decode,
+// and without interceptors nothing else has seen the response. Otherwise it's left to the garbage collector
+func (db *DB) release(result *internal.RPCRawResponse) {
+	switch db.codec.(type) {
+	case Config.GoccyCodec, *Config.GoccyCodec, Config.StdCodec, *Config.StdCodec:
+	default:
+		return
+	}
 
-	chn := db.ws.Once(id, method)
-	// here we send the args through our websocket connection
-	db.ws.Send(id, method, params)
+	db.interceptors.lock.RLock()
+	intercepted := len(db.interceptors.chain) > 0
+	db.interceptors.lock.RUnlock()
 
-	ctx, cancel := db.ws.NewContext()
-	defer cancel()
+	if !intercepted {
+		result.Release()
+	}
+}
 
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
+// invoke sends the request over the transport, it's the end of the interceptor chain
+func (db *DB) invoke(ctx context.Context, method string, params []any) (*internal.RPCRawResponse, error) {
+	result, err := db.roundTrip(ctx, method, params, func(ctx context.Context, id string) (*internal.RPCRawResponse, error) {
+		return db.transport.Send(ctx, id, method, params)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	case r := <-chn:
-		if r.Err != nil {
-			return nil, r.Err
+	// keep track of the session state, so the transport can replay it when reconnecting
+	if session, ok := db.transport.(sessionTransport); ok {
+		if change, ok := newSessionChange(method, params, result); ok {
+			change.apply(session)
 		}
+	}
 
-		return r.Value, nil
+	db.rememberAuth(method, params, result)
+	if tokens := db.tokenManager(); tokens != nil {
+		tokens.observe(method, params, result)
 	}
+
+	return result, nil
 }
@@ -0,0 +1,340 @@
+package surrealdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+func TestDB_ReconnectReplaysSession(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Signin(surrealdb.UserInfo{User: "root", Password: "root"})
+	require.NoError(t, err)
+	_, err = db.Use("test", "test")
+	require.NoError(t, err)
+	_, err = db.Let("tenant", "acme")
+	require.NoError(t, err)
+
+	mock.dropConnections()
+	mock.waitForConnection(1)
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"signin", "use", "let", "query"}, mock.methods(1))
+}
+
+func TestReconnectConfig_Delay(t *testing.T) {
+	config := &Config.DbReconnectConfig{InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	require.Equal(t, time.Second, config.Delay(0))
+	require.Equal(t, 2*time.Second, config.Delay(1))
+	require.Equal(t, 4*time.Second, config.Delay(2))
+	require.Equal(t, 5*time.Second, config.Delay(3))
+}
+
+func TestDB_SendCtxHonoursCancellation(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		<-block
+		return nil, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err = db.SendCtx(ctx, "query", "SELECT * FROM user", map[string]any{})
+
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+	require.True(t, time.Since(started) < time.Second)
+}
+
+func TestDB_InFlightRequestsFailWhenConnectionDrops(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		<-block
+		return nil, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	errs := make(chan error)
+	go func() {
+		_, err := db.Query("SELECT * FROM user", map[string]any{})
+		errs <- err
+	}()
+
+	// give the request time to be written before dropping the connection
+	time.Sleep(50 * time.Millisecond)
+	mock.dropConnections()
+
+	select {
+	case err := <-errs:
+		require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+	case <-time.After(time.Second):
+		t.Fatal("expected the in-flight request to fail when the connection dropped")
+	}
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+}
+
+func TestDB_CloseFailsInFlightRequests(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		<-block
+		return nil, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+
+	errs := make(chan error)
+	go func() {
+		_, err := db.Query("SELECT * FROM user", map[string]any{})
+		errs <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	db.Close()
+
+	select {
+	case err := <-errs:
+		require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+	case <-time.After(time.Second):
+		t.Fatal("expected the in-flight request to fail when the db was closed")
+	}
+}
+
+func TestDB_DialOptions(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return strings.Repeat("a", 1024), ""
+	})
+
+	config := mock.config()
+	config.Dial = &Config.DbDialConfig{
+		Headers:            http.Header{"X-Tenant": []string{"acme"}},
+		ReadLimit:          512,
+		DisableCompression: true,
+	}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	handshake := mock.handshake(0)
+	require.Equal(t, "acme", handshake.Get("X-Tenant"))
+	require.Empty(t, handshake.Get("Sec-Websocket-Extensions"))
+
+	// the response is larger than the read limit, so the connection is closed
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+}
+
+func TestDB_KeepaliveKeepsHealthyConnectionsOpen(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	config := mock.config()
+	config.Keepalive = &Config.DbKeepaliveConfig{Interval: 20 * time.Millisecond, Timeout: 30 * time.Millisecond}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// several heartbeats pass without any requests, the pongs keep the connection alive
+	time.Sleep(200 * time.Millisecond)
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"query"}, mock.methods(0))
+}
+
+func TestDB_KeepaliveWithoutIntervalUsesTheDefault(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	// built by hand, so it hasn't been through ParseDSN
+	config := &Config.DbConfig{
+		Url:       mock.config().Url,
+		Keepalive: &Config.DbKeepaliveConfig{Timeout: time.Second},
+	}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+}
+
+func TestDB_KeepaliveMissedHeartbeatReconnects(t *testing.T) {
+	mock := newMockServer(t, nil)
+	mock.setIgnorePings(true)
+
+	config := mock.config()
+	config.Keepalive = &Config.DbKeepaliveConfig{Interval: 20 * time.Millisecond, Timeout: 30 * time.Millisecond}
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 10 * time.Millisecond}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Use("test", "test")
+	require.NoError(t, err)
+
+	// the first connection never answers a ping, so it's dropped and the session replayed on a new one
+	mock.setIgnorePings(false)
+	mock.waitForConnection(1)
+
+	waitFor(t, time.Second, func() bool { return len(mock.methods(1)) > 0 })
+	require.Equal(t, []string{"use"}, mock.methods(1))
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+}
+
+func TestDB_KeepaliveMissedHeartbeatFailsRequests(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		<-block
+		return nil, ""
+	})
+	mock.setIgnorePings(true)
+
+	config := mock.config()
+	config.Timeouts = &Config.DbTimeoutConfig{Timeout: 5 * time.Second}
+	config.Keepalive = &Config.DbKeepaliveConfig{Interval: 20 * time.Millisecond, Timeout: 30 * time.Millisecond}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the request fails as soon as the heartbeat is missed, rather than waiting for its own timeout
+	started := time.Now()
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+	require.True(t, time.Since(started) < time.Second)
+}
+
+func TestDB_EncodeErrorsOnlyFailTheirRequest(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Query("SELECT * FROM user WHERE tags = $tags", map[string]any{"tags": make(chan int)})
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "failed to encode the query request"), err.Error())
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"query"}, mock.methods(0))
+}
+
+func TestDB_SendQueueKeepsOrderAndFailsFast(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 300 * time.Millisecond}
+	config.SendQueue = &Config.DbSendQueueConfig{Size: 2, FailFast: true}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// nothing is written whilst we're reconnecting, so the requests wait in the queue
+	mock.dropConnections()
+	time.Sleep(50 * time.Millisecond)
+
+	errs := make(chan error, 2)
+	for _, method := range []string{"first", "second"} {
+		method := method
+		go func() {
+			_, err := db.SendCtx(context.Background(), method)
+			errs <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	_, err = db.SendCtx(context.Background(), "third")
+	require.Equal(t, surrealdb.ErrQueueFull, err)
+
+	mock.waitForConnection(1)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+
+	require.Equal(t, []string{"first", "second"}, mock.methods(1))
+}
+
+func TestDB_SendQueueBlocksUntilTheContextIsDone(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 300 * time.Millisecond}
+	config.SendQueue = &Config.DbSendQueueConfig{Size: 1}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.dropConnections()
+	time.Sleep(50 * time.Millisecond)
+
+	go db.SendCtx(context.Background(), "first")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = db.SendCtx(ctx, "second")
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "expected a deadline error, got %v", err)
+}
+
+func TestDB_ValidatesConfigsBuiltInCode(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{MaxRetries: -1}
+
+	_, err := surrealdb.New(config)
+	require.True(t, errors.Is(err, Config.ErrInvalidConfig), err)
+
+	_, err = surrealdb.NewWithInterceptors(config)
+	require.True(t, errors.Is(err, Config.ErrInvalidConfig), err)
+
+	_, err = surrealdb.NewPool(config, &Config.DbPoolConfig{MinConnections: 1, MaxConnections: 1})
+	require.True(t, errors.Is(err, Config.ErrInvalidConfig), err)
+
+	// nothing was dialed
+	require.Len(t, mock.connections, 0)
+}
@@ -1,9 +1,36 @@
 package surrealdb
 
-type PermissionError struct {
-	what string
-}
+import (
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
 
-func (pe PermissionError) Error() string {
-	return "Unable to access record: " + pe.what
-}
+// The errors returned by DB are one of these types, check for them with errors.As, or check the kind of error
+// with errors.Is and the Err* values below. Errors from the database all unwrap to the *RPCError they came from
+type (
+	// RPCError is an error returned by the database, with its json-rpc code
+	RPCError = internal.RPCError
+	// ConnectionError is returned when the request couldn't be sent, or the connection was lost before its response arrived
+	ConnectionError = internal.ConnectionError
+	// TimeoutError is returned when the response didn't arrive before the deadline of the request
+	TimeoutError = internal.TimeoutError
+	// AuthError is returned when the credentials are wrong, or the session isn't(or is no longer) authenticated
+	AuthError = internal.AuthError
+	// PermissionError is returned when the session isn't allowed to do what was asked
+	PermissionError = internal.PermissionError
+	// ParseError is returned when the query couldn't be parsed, with the line and column when the database gives them
+	ParseError = internal.ParseError
+	// ConflictError is returned when a record already exists, or a transaction conflicted with another one
+	ConflictError = internal.ConflictError
+	// StatementError is returned when a statement of a query failed, it unwraps to the type of error it was
+	StatementError = internal.StatementError
+)
+
+var (
+	ErrConnection = internal.ErrConnection
+	ErrTimeout    = internal.ErrTimeout
+	ErrAuth       = internal.ErrAuth
+	ErrPermission = internal.ErrPermission
+	ErrParse      = internal.ErrParse
+	ErrConflict   = internal.ErrConflict
+	ErrStatement  = internal.ErrStatement
+)
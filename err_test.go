@@ -0,0 +1,110 @@
+package surrealdb_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+func TestErrors_ClassifiedFromTheDatabase(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		switch req.Method {
+		case "signin":
+			return nil, "There was a problem with authentication"
+		case "select":
+			return nil, "IAM error: Not enough permissions to perform this action"
+		case "create":
+			return nil, "Database record `user:1` already exists"
+		case "query":
+			return nil, "Parse error on line 3 at character 12 when parsing 'SELEC * FROM user'"
+		}
+		return nil, "There was a problem with the database"
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Signin(surrealdb.UserInfo{User: "root", Password: "wrong"})
+	var authErr *surrealdb.AuthError
+	require.True(t, errors.As(err, &authErr), err)
+	require.True(t, errors.Is(err, surrealdb.ErrAuth))
+	require.Equal(t, "There was a problem with authentication", authErr.Message)
+
+	_, err = db.SendCtx(context.Background(), "select", "user")
+	require.True(t, errors.Is(err, surrealdb.ErrPermission), err)
+
+	_, err = db.SendCtx(context.Background(), "create", "user:1", map[string]any{"username": "bob"})
+	var conflictErr *surrealdb.ConflictError
+	require.True(t, errors.As(err, &conflictErr), err)
+
+	_, err = db.Query("SELEC * FROM user", nil)
+	var parseErr *surrealdb.ParseError
+	require.True(t, errors.As(err, &parseErr), err)
+	require.Equal(t, 3, parseErr.Line)
+	require.Equal(t, 12, parseErr.Column)
+
+	// everything from the database still unwraps to the rpc error
+	var rpcErr *surrealdb.RPCError
+	require.True(t, errors.As(err, &rpcErr))
+
+	_, err = db.Info()
+	require.True(t, errors.As(err, &rpcErr))
+	require.False(t, errors.Is(err, surrealdb.ErrParse) || errors.Is(err, surrealdb.ErrAuth))
+}
+
+func TestErrors_ConnectionAndTimeout(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "query" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return "", ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = db.QueryCtx(ctx, "SELECT * FROM user", nil)
+	var timeoutErr *surrealdb.TimeoutError
+	require.True(t, errors.As(err, &timeoutErr), err)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	require.NoError(t, db.Close())
+
+	_, err = db.Info()
+	var connErr *surrealdb.ConnectionError
+	require.True(t, errors.As(err, &connErr), err)
+	require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed))
+
+	_, err = surrealdb.New(&Config.DbConfig{Url: "ws://127.0.0.1:1/rpc"})
+	require.True(t, errors.Is(err, surrealdb.ErrConnection), err)
+}
+
+func TestErrors_StatementErrorsOverHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"status":"ERR","time":"1ms","detail":"You don't have permission to view this table"}]`))
+	}))
+	defer server.Close()
+
+	db, err := surrealdb.New(&Config.DbConfig{Url: server.URL})
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.SendCtx(context.Background(), "select", "secret")
+
+	var statementErr *surrealdb.StatementError
+	require.True(t, errors.As(err, &statementErr), err)
+	require.Equal(t, 0, statementErr.Index)
+	require.Equal(t, "ERR", statementErr.Status)
+	require.True(t, errors.Is(err, surrealdb.ErrPermission), err)
+}
@@ -0,0 +1,56 @@
+package surrealdb
+
+import (
+	"context"
+)
+
+// Invoker sends a request to the database and returns its response
+type Invoker func(ctx context.Context, method string, params []any) (*RawResponse, error)
+
+// Interceptor wraps every request made through the DB, including the ones made by Query[T], the QueryBuilder
+// and the other typed helpers. It can inspect or rewrite the method and params before calling next,
+// inspect or replace the response it returns, or block the request by returning an error without calling next
+// Params are shared with the caller, so build a new slice to change them rather than modifying it
+// Once any interceptor is added, responses aren't returned to the buffer pool, so they can be kept
+type Interceptor func(next Invoker) Invoker
+
+// Chain composes interceptors into a single one, the first one is the outermost
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(next Invoker) Invoker {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next = interceptors[i](next)
+		}
+
+		return next
+	}
+}
+
+// Intercept adds interceptors to the DB, they run inside the ones added before them
+// To also intercept the requests made by New(AutoLogin and AutoUse), use NewWithInterceptors
+func (db *DB) Intercept(interceptors ...Interceptor) {
+	db.interceptors.lock.Lock()
+	defer db.interceptors.lock.Unlock()
+
+	db.interceptors.chain = append(db.interceptors.chain, interceptors...)
+	db.interceptors.invoker = Chain(db.interceptors.chain...)(db.invoke)
+}
+
+// intercepted wraps invoker in the interceptors
+func (db *DB) intercepted(invoker Invoker) Invoker {
+	db.interceptors.lock.RLock()
+	defer db.interceptors.lock.RUnlock()
+
+	return Chain(db.interceptors.chain...)(invoker)
+}
+
+// invoker returns the interceptor chain, ending in invoke
+func (db *DB) invoker() Invoker {
+	db.interceptors.lock.RLock()
+	defer db.interceptors.lock.RUnlock()
+
+	if db.interceptors.invoker == nil {
+		return db.invoke
+	}
+
+	return db.interceptors.invoker
+}
@@ -0,0 +1,204 @@
+package surrealdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	"github.com/test-go/testify/require"
+)
+
+var errUnsafeDelete = errors.New("DELETE without a WHERE clause")
+
+// withTimeout adds a TIMEOUT clause to every query
+func withTimeout(next surrealdb.Invoker) surrealdb.Invoker {
+	return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+		if method == "query" && len(params) > 0 {
+			if sql, ok := params[0].(string); ok {
+				params = append([]any{sql + " TIMEOUT 5s"}, params[1:]...)
+			}
+		}
+
+		return next(ctx, method, params)
+	}
+}
+
+// blockUnsafeDeletes refuses deletes of a whole table
+func blockUnsafeDeletes(next surrealdb.Invoker) surrealdb.Invoker {
+	return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+		if method == "query" && len(params) > 0 {
+			sql := strings.ToUpper(params[0].(string))
+			if strings.HasPrefix(sql, "DELETE") && !strings.Contains(sql, "WHERE") {
+				return nil, errUnsafeDelete
+			}
+		}
+
+		return next(ctx, method, params)
+	}
+}
+
+func (mock *mockServer) sentQueries(conn int) []string {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	var queries []string
+	for _, req := range mock.requests[conn] {
+		if req.Method != "query" {
+			continue
+		}
+		var sql string
+		json.Unmarshal(req.Params[0], &sql)
+		queries = append(queries, sql)
+	}
+
+	return queries
+}
+
+func TestInterceptor_RewritesAndBlocksRequests(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob"}}}}, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.Intercept(blockUnsafeDeletes, withTimeout)
+
+	_, err = db.Query("DELETE user", nil)
+	require.True(t, errors.Is(err, errUnsafeDelete), err)
+
+	user := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user").First()
+	require.NotNil(t, user)
+	require.Equal(t, "bob", user.Username)
+
+	result := surrealdb.NewBuilder[liveUser]("user").On(db).Execute()
+	require.NoError(t, result.Error())
+
+	_, err = db.Query("DELETE user WHERE id = user:1", nil)
+	require.NoError(t, err)
+
+	queries := mock.sentQueries(0)
+	require.Len(t, queries, 3)
+	require.Equal(t, "SELECT * FROM user TIMEOUT 5s", queries[0])
+	require.True(t, strings.HasSuffix(queries[1], " TIMEOUT 5s"), queries[1])
+	require.Equal(t, "DELETE user WHERE id = user:1 TIMEOUT 5s", queries[2])
+}
+
+func TestInterceptor_ReplacesResults(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob"}}}}, ""
+	})
+
+	var order []string
+	record := func(name string) surrealdb.Interceptor {
+		return func(next surrealdb.Invoker) surrealdb.Invoker {
+			return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+				order = append(order, name+" "+method)
+				return next(ctx, method, params)
+			}
+		}
+	}
+
+	hideUsernames := func(next surrealdb.Invoker) surrealdb.Invoker {
+		return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+			response, err := next(ctx, method, params)
+			if err != nil || method != "query" {
+				return response, err
+			}
+			response.Release()
+
+			return surrealdb.NewRawResponse([]byte(`{"id":"1","result":[{"status":"OK","time":"1ms","result":[{"id":"user:1","username":"hidden"}]}]}`)), nil
+		}
+	}
+
+	config := mock.config()
+	config.Username = "root"
+	config.Password = "root"
+	config.AutoLogin = true
+
+	db, err := surrealdb.NewWithInterceptors(config, surrealdb.Chain(record("first"), record("second")), hideUsernames)
+	require.NoError(t, err)
+	defer db.Close()
+
+	user := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user").First()
+	require.NotNil(t, user)
+	require.Equal(t, "hidden", user.Username)
+
+	require.Equal(t, []string{"first signin", "second signin", "first query", "second query"}, order)
+}
+
+func TestInterceptor_SeesLiveQueries(t *testing.T) {
+	mock := newMockServer(t, liveHandler)
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	var liveIds []string
+	db.Intercept(func(next surrealdb.Invoker) surrealdb.Invoker {
+		return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+			response, err := next(ctx, method, params)
+			if err == nil && method == "live" {
+				id, _ := response.LiveQueryId()
+				liveIds = append(liveIds, id)
+			}
+			return response, err
+		}
+	})
+
+	sub, err := surrealdb.LiveQuery[liveUser](db, "user")
+	require.NoError(t, err)
+	defer sub.Close()
+
+	require.Equal(t, []string{liveQueryId}, liveIds)
+}
+
+func TestInterceptor_SeesKills(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "select" {
+			return []any{}, ""
+		}
+		return liveHandler(req)
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	var kills []string
+	blockKills := true
+	db.Intercept(func(next surrealdb.Invoker) surrealdb.Invoker {
+		return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+			if method != "kill" {
+				return next(ctx, method, params)
+			}
+			kills = append(kills, params[0].(string))
+			if blockKills {
+				return nil, errUnsafeDelete
+			}
+			return next(ctx, method, params)
+		}
+	})
+
+	sub, err := surrealdb.LiveQuery[liveUser](db, "user")
+	require.NoError(t, err)
+	require.True(t, errors.Is(sub.Close(), errUnsafeDelete))
+
+	blockKills = false
+	liveId, err := db.Live("user")
+	require.NoError(t, err)
+	_, err = db.Kill(liveId.(string))
+	require.NoError(t, err)
+
+	collection, err := surrealdb.NewLiveCollection[liveUser](db, "user")
+	require.NoError(t, err)
+	require.NoError(t, collection.Close())
+
+	require.Equal(t, []string{liveQueryId, liveQueryId, liveQueryId}, kills)
+	// the blocked kill never reached the database
+	require.Equal(t, []string{"live", "live", "kill", "live", "select", "kill"}, mock.methods(0))
+}
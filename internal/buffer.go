@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer buffers which grew larger than this aren't pooled,
+// so one huge response doesn't keep its memory around forever
+const maxPooledBuffer = 32 << 20
+
+// bufferPool holds the buffers messages are read into and encoded into
+var bufferPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,1211 @@
+package internal
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goccy/go-json"
+)
+
+// CBOR(https://www.rfc-editor.org/rfc/rfc8949) is used by the binary rpc protocol
+// Requests are encoded straight from the go values, so typed values are sent as their SurrealDB tags
+// Responses are transcoded into json, so RPCRawResponse and the resolvers work the same for both protocols
+
+// The major types, already shifted into the top 3 bits of the initial byte
+const (
+	cborUnsigned byte = 0 << 5
+	cborNegative byte = 1 << 5
+	cborBytes    byte = 2 << 5
+	cborText     byte = 3 << 5
+	cborArray    byte = 4 << 5
+	cborMap      byte = 5 << 5
+	cborTag      byte = 6 << 5
+	cborSimple   byte = 7 << 5
+
+	cborIndefinite byte = 31
+	cborBreak      byte = 0xff
+
+	// maxCBORDepth is how deep arrays, maps and tags can be nested in a message we decode
+	maxCBORDepth = 512
+)
+
+// The tags SurrealDB uses for its own types
+const (
+	TagDatetimeString = 0
+	TagNone           = 6
+	TagTable          = 7
+	TagRecordID       = 8
+	TagUUIDString     = 9
+	TagDecimal        = 10
+	TagDatetime       = 12
+	TagDurationString = 13
+	TagDuration       = 14
+	TagUUID           = 37
+)
+
+var (
+	// ErrInvalidCBOR is returned when a binary message can't be decoded
+	ErrInvalidCBOR = errors.New("invalid cbor")
+	// ErrUnsupportedCBORType is returned when a value can't be encoded as cbor
+	ErrUnsupportedCBORType = errors.New("unsupported type for cbor")
+)
+
+// CBORTagger is implemented by values which are sent as a cbor tag, like record ids and durations
+type CBORTagger interface {
+	// CBORTag returns the tag number, and the value which is encoded as its content
+	CBORTag() (uint64, any)
+}
+
+// MarshalCBOR encodes v as cbor
+func MarshalCBOR(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := EncodeCBOR(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// EncodeCBOR encodes v as cbor into buf
+// Structs use their json tags, and values implementing json.Marshaler(but not CBORTagger) are encoded through their json
+func EncodeCBOR(buf *bytes.Buffer, v any) error {
+	enc := &cborEncoder{buf: buf}
+
+	return enc.encode(reflect.ValueOf(v))
+}
+
+// CBORToJSON transcodes a single cbor item into json, SurrealDB's tags become the same values the json protocol uses
+func CBORToJSON(dst *bytes.Buffer, data []byte) error {
+	dec := &cborDecoder{data: data}
+
+	if err := dec.transcode(dst); err != nil {
+		return err
+	}
+	if dec.pos != len(data) {
+		return fmt.Errorf("%w: %d bytes left after the value", ErrInvalidCBOR, len(data)-dec.pos)
+	}
+
+	return nil
+}
+
+// --------------------------------------------------
+// Encoding
+// --------------------------------------------------
+
+type cborEncoder struct {
+	buf *bytes.Buffer
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (enc *cborEncoder) head(major byte, n uint64) {
+	switch {
+	case n < 24:
+		enc.buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		enc.buf.WriteByte(major | 24)
+		enc.buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		enc.buf.WriteByte(major | 25)
+		enc.uint16(uint16(n))
+	case n <= math.MaxUint32:
+		enc.buf.WriteByte(major | 26)
+		enc.uint32(uint32(n))
+	default:
+		enc.buf.WriteByte(major | 27)
+		enc.uint64(n)
+	}
+}
+
+func (enc *cborEncoder) uint16(n uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], n)
+	enc.buf.Write(b[:])
+}
+
+func (enc *cborEncoder) uint32(n uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	enc.buf.Write(b[:])
+}
+
+func (enc *cborEncoder) uint64(n uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	enc.buf.Write(b[:])
+}
+
+func (enc *cborEncoder) null() {
+	enc.buf.WriteByte(cborSimple | 22)
+}
+
+func (enc *cborEncoder) int(n int64) {
+	if n >= 0 {
+		enc.head(cborUnsigned, uint64(n))
+		return
+	}
+
+	enc.head(cborNegative, uint64(-1-n))
+}
+
+func (enc *cborEncoder) text(s string) {
+	enc.head(cborText, uint64(len(s)))
+	enc.buf.WriteString(s)
+}
+
+func (enc *cborEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		enc.null()
+		return nil
+	}
+
+	if handled, err := enc.encodeSpecial(v); handled {
+		return err
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			enc.null()
+			return nil
+		}
+		return enc.encode(v.Elem())
+
+	case reflect.Bool:
+		if v.Bool() {
+			enc.buf.WriteByte(cborSimple | 21)
+		} else {
+			enc.buf.WriteByte(cborSimple | 20)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		enc.int(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		enc.head(cborUnsigned, v.Uint())
+
+	case reflect.Float32:
+		enc.buf.WriteByte(cborSimple | 26)
+		enc.uint32(math.Float32bits(float32(v.Float())))
+
+	case reflect.Float64:
+		enc.buf.WriteByte(cborSimple | 27)
+		enc.uint64(math.Float64bits(v.Float()))
+
+	case reflect.String:
+		enc.text(v.String())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			enc.null()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			enc.head(cborBytes, uint64(v.Len()))
+			enc.buf.Write(v.Bytes())
+			return nil
+		}
+		return enc.encodeArray(v)
+
+	case reflect.Array:
+		return enc.encodeArray(v)
+
+	case reflect.Map:
+		return enc.encodeMap(v)
+
+	case reflect.Struct:
+		return enc.encodeStruct(v)
+
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedCBORType, v.Type())
+	}
+
+	return nil
+}
+
+// encodeSpecial handles the types which control how they're encoded, it returns false when v isn't one of them
+func (enc *cborEncoder) encodeSpecial(v reflect.Value) (bool, error) {
+	// pointers are dereferenced first, the value they point to is addressable so pointer receivers are found below
+	if kind := v.Kind(); kind == reflect.Pointer || kind == reflect.Interface {
+		return false, nil
+	}
+
+	if v.Type() == timeType {
+		value := v.Interface().(time.Time)
+		enc.head(cborTag, TagDatetime)
+		enc.head(cborArray, 2)
+		enc.int(value.Unix())
+		enc.int(int64(value.Nanosecond()))
+		return true, nil
+	}
+
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.CanInterface() {
+		return false, nil
+	}
+
+	switch value := v.Interface().(type) {
+	case CBORTagger:
+		tag, content := value.CBORTag()
+		enc.head(cborTag, tag)
+		return true, enc.encode(reflect.ValueOf(content))
+
+	case json.Marshaler:
+		data, err := value.MarshalJSON()
+		if err != nil {
+			return true, err
+		}
+		return true, enc.encodeJSON(data)
+
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return true, err
+		}
+		enc.text(string(text))
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// encodeJSON encodes a value which has already been encoded as json
+func (enc *cborEncoder) encodeJSON(data []byte) error {
+	var value any
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	return enc.encodeDecoded(value)
+}
+
+// encodeDecoded encodes the values json decodes into, keeping integers as integers
+func (enc *cborEncoder) encodeDecoded(value any) error {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			enc.int(n)
+			return nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.encode(reflect.ValueOf(f))
+
+	case []any:
+		enc.head(cborArray, uint64(len(value)))
+		for _, item := range value {
+			if err := enc.encodeDecoded(item); err != nil {
+				return err
+			}
+		}
+
+	case map[string]any:
+		enc.head(cborMap, uint64(len(value)))
+		for key, item := range value {
+			enc.text(key)
+			if err := enc.encodeDecoded(item); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return enc.encode(reflect.ValueOf(value))
+	}
+
+	return nil
+}
+
+func (enc *cborEncoder) encodeArray(v reflect.Value) error {
+	enc.head(cborArray, uint64(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if err := enc.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *cborEncoder) encodeMap(v reflect.Value) error {
+	if v.IsNil() {
+		enc.null()
+		return nil
+	}
+
+	enc.head(cborMap, uint64(v.Len()))
+
+	iter := v.MapRange()
+	for iter.Next() {
+		// objects only have string keys, so other keys are converted like encoding/json does
+		key := iter.Key()
+		switch {
+		case key.Kind() == reflect.String:
+			enc.text(key.String())
+		case key.Type().Implements(textMarshalerType):
+			text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return err
+			}
+			enc.text(string(text))
+		case key.CanInt():
+			enc.text(strconv.FormatInt(key.Int(), 10))
+		case key.CanUint():
+			enc.text(strconv.FormatUint(key.Uint(), 10))
+		default:
+			return fmt.Errorf("%w: map key %s", ErrUnsupportedCBORType, key.Type())
+		}
+
+		if err := enc.encode(iter.Value()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (enc *cborEncoder) encodeStruct(v reflect.Value) error {
+	fields := cachedCBORFields(v.Type())
+
+	values := make([]reflect.Value, 0, len(fields))
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, ok := fieldByIndex(v, field.index)
+		if !ok || (field.omitEmpty && isEmptyValue(value)) {
+			continue
+		}
+
+		values = append(values, value)
+		names = append(names, field.name)
+	}
+
+	enc.head(cborMap, uint64(len(values)))
+	for i, value := range values {
+		enc.text(names[i])
+		if err := enc.encode(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cborField is an exported struct field, named by its json tag
+type cborField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var cborFieldCache sync.Map
+
+func cachedCBORFields(t reflect.Type) []cborField {
+	if fields, ok := cborFieldCache.Load(t); ok {
+		return fields.([]cborField)
+	}
+
+	fields := structFields(t, nil)
+	cborFieldCache.Store(t, fields)
+
+	return fields
+}
+
+func structFields(t reflect.Type, index []int) []cborField {
+	var fields []cborField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldIndex := append(append([]int{}, index...), i)
+
+		// embedded structs without a name have their fields promoted, like encoding/json
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, structFields(embedded, fieldIndex)...)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, cborField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(options, "omitempty"),
+		})
+	}
+
+	return fields
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, which returns false instead of panicking on a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(idx)
+	}
+
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// --------------------------------------------------
+// Decoding
+// --------------------------------------------------
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+	// depth is how many arrays, maps and tags the next item is nested in
+	depth int
+}
+
+// jsonNumber is the text of a number, written into the json as it is
+type jsonNumber string
+
+func (dec *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	if dec.pos >= len(dec.data) {
+		return 0, 0, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+
+	initial := dec.data[dec.pos]
+	dec.pos++
+
+	major, info = initial&0xe0, initial&0x1f
+
+	size := 0
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	case info == cborIndefinite:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("%w: reserved additional information %d", ErrInvalidCBOR, info)
+	}
+
+	if dec.pos+size > len(dec.data) {
+		return 0, 0, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+	}
+
+	for _, b := range dec.data[dec.pos : dec.pos+size] {
+		arg = arg<<8 | uint64(b)
+	}
+	dec.pos += size
+
+	return major, info, arg, nil
+}
+
+// enter is called when decoding the content of an array, map or tag, and leave once it's done
+// They aren't balanced when decoding fails, as the decoder isn't used after that
+// Messages are only ever nested a few levels, so a deep one is rejected before it can overflow the stack
+func (dec *cborDecoder) enter() error {
+	if dec.depth++; dec.depth > maxCBORDepth {
+		return fmt.Errorf("%w: nested deeper than %d", ErrInvalidCBOR, maxCBORDepth)
+	}
+
+	return nil
+}
+
+func (dec *cborDecoder) leave() {
+	dec.depth--
+}
+
+// checkCount rejects arrays and maps which declare more items than the rest of the data could hold,
+// every item takes at least one byte, and map items are a key and a value
+func (dec *cborDecoder) checkCount(info byte, count uint64, itemSize uint64) error {
+	if info != cborIndefinite && count > uint64(len(dec.data)-dec.pos)/itemSize {
+		return fmt.Errorf("%w: %d items declared, but only %d bytes are left", ErrInvalidCBOR, count, len(dec.data)-dec.pos)
+	}
+
+	return nil
+}
+
+// atBreak consumes the break which ends an indefinite length item, when it's next
+func (dec *cborDecoder) atBreak() bool {
+	if dec.pos < len(dec.data) && dec.data[dec.pos] == cborBreak {
+		dec.pos++
+		return true
+	}
+
+	return false
+}
+
+// bytes reads the content of a byte or text string, joining the chunks of indefinite length strings
+func (dec *cborDecoder) bytes(major byte, info byte, arg uint64) ([]byte, error) {
+	if info != cborIndefinite {
+		if arg > uint64(len(dec.data)-dec.pos) {
+			return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCBOR)
+		}
+		content := dec.data[dec.pos : dec.pos+int(arg)]
+		dec.pos += int(arg)
+		return content, nil
+	}
+
+	var content []byte
+	for !dec.atBreak() {
+		chunkMajor, chunkInfo, chunkArg, err := dec.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, fmt.Errorf("%w: invalid chunk in an indefinite length string", ErrInvalidCBOR)
+		}
+		chunk, err := dec.bytes(chunkMajor, chunkInfo, chunkArg)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, chunk...)
+	}
+
+	return content, nil
+}
+
+// transcode writes the next item as json
+func (dec *cborDecoder) transcode(w *bytes.Buffer) error {
+	major, info, arg, err := dec.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case cborUnsigned:
+		w.WriteString(strconv.FormatUint(arg, 10))
+
+	case cborNegative:
+		w.WriteString(negativeInteger(arg))
+
+	case cborBytes:
+		content, err := dec.bytes(major, info, arg)
+		if err != nil {
+			return err
+		}
+		w.WriteByte('"')
+		w.WriteString(base64.StdEncoding.EncodeToString(content))
+		w.WriteByte('"')
+
+	case cborText:
+		content, err := dec.bytes(major, info, arg)
+		if err != nil {
+			return err
+		}
+		writeJSONString(w, content)
+
+	case cborArray:
+		if err := dec.checkCount(info, arg, 1); err != nil {
+			return err
+		}
+		if err := dec.enter(); err != nil {
+			return err
+		}
+		w.WriteByte('[')
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && dec.atBreak() {
+				break
+			}
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := dec.transcode(w); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+		dec.leave()
+
+	case cborMap:
+		if err := dec.checkCount(info, arg, 2); err != nil {
+			return err
+		}
+		if err := dec.enter(); err != nil {
+			return err
+		}
+		w.WriteByte('{')
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && dec.atBreak() {
+				break
+			}
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := dec.transcodeKey(w); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+			if err := dec.transcode(w); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('}')
+		dec.leave()
+
+	case cborTag:
+		value, err := dec.tag(arg)
+		if err != nil {
+			return err
+		}
+		return writeJSONValue(w, value)
+
+	case cborSimple:
+		value, err := dec.simple(info, arg)
+		if err != nil {
+			return err
+		}
+		return writeJSONValue(w, value)
+	}
+
+	return nil
+}
+
+// transcodeKey writes a map key, keys which aren't text are written as the string of their json
+func (dec *cborDecoder) transcodeKey(w *bytes.Buffer) error {
+	if dec.pos < len(dec.data) && dec.data[dec.pos]&0xe0 == cborText {
+		return dec.transcode(w)
+	}
+
+	var key bytes.Buffer
+	if err := dec.transcode(&key); err != nil {
+		return err
+	}
+	writeJSONString(w, key.Bytes())
+
+	return nil
+}
+
+// value decodes the next item into a go value, this is used for the content of tags
+func (dec *cborDecoder) value() (any, error) {
+	major, info, arg, err := dec.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		return arg, nil
+
+	case cborNegative:
+		if arg < math.MaxInt64 {
+			return -1 - int64(arg), nil
+		}
+		return jsonNumber(negativeInteger(arg)), nil
+
+	case cborBytes:
+		return dec.bytes(major, info, arg)
+
+	case cborText:
+		content, err := dec.bytes(major, info, arg)
+		return string(content), err
+
+	case cborArray:
+		if err := dec.checkCount(info, arg, 1); err != nil {
+			return nil, err
+		}
+		if err := dec.enter(); err != nil {
+			return nil, err
+		}
+
+		items := []any{}
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && dec.atBreak() {
+				break
+			}
+			item, err := dec.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		dec.leave()
+		return items, nil
+
+	case cborMap:
+		if err := dec.checkCount(info, arg, 2); err != nil {
+			return nil, err
+		}
+		if err := dec.enter(); err != nil {
+			return nil, err
+		}
+
+		items := map[string]any{}
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && dec.atBreak() {
+				break
+			}
+			key, err := dec.value()
+			if err != nil {
+				return nil, err
+			}
+			item, err := dec.value()
+			if err != nil {
+				return nil, err
+			}
+			items[fmt.Sprint(key)] = item
+		}
+		dec.leave()
+		return items, nil
+
+	case cborTag:
+		return dec.tag(arg)
+	}
+
+	return dec.simple(info, arg)
+}
+
+// tag decodes the content of a tag, SurrealDB's own tags are converted into the values the json protocol uses
+func (dec *cborDecoder) tag(tag uint64) (any, error) {
+	if err := dec.enter(); err != nil {
+		return nil, err
+	}
+	content, err := dec.value()
+	dec.leave()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case TagNone:
+		return nil, nil
+
+	case TagRecordID:
+		parts, ok := content.([]any)
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("%w: a record id should be an array of the table and id", ErrInvalidCBOR)
+		}
+		table, ok := parts[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: the table of a record id should be a string", ErrInvalidCBOR)
+		}
+		return FormatRecordID(table, parts[1]), nil
+
+	case TagUUID:
+		id, ok := content.([]byte)
+		if !ok || len(id) != 16 {
+			return nil, fmt.Errorf("%w: a uuid should be 16 bytes", ErrInvalidCBOR)
+		}
+		return formatUUID(id), nil
+
+	case TagDecimal:
+		value, ok := content.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: a decimal should be a string", ErrInvalidCBOR)
+		}
+		// the text is kept as it is, so no precision is lost when it's decoded
+		if isJSONNumber(value) {
+			return jsonNumber(value), nil
+		}
+		return value, nil
+
+	case TagDatetime:
+		seconds, nanoseconds, err := secondsAndNanos(content)
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(seconds, nanoseconds).UTC().Format(time.RFC3339Nano), nil
+
+	case TagDuration:
+		seconds, nanoseconds, err := secondsAndNanos(content)
+		if err != nil {
+			return nil, err
+		}
+		return FormatDuration(time.Duration(seconds)*time.Second + time.Duration(nanoseconds)), nil
+	}
+
+	// the string tags(table, uuid, datetime and duration strings) and any tag we don't know are their content
+	return content, nil
+}
+
+// simple decodes the major type 7 values, booleans, null and floats
+func (dec *cborDecoder) simple(info byte, arg uint64) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return float64(halfToFloat32(uint16(arg))), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	case cborIndefinite:
+		return nil, fmt.Errorf("%w: unexpected break", ErrInvalidCBOR)
+	}
+
+	return nil, fmt.Errorf("%w: unsupported simple value %d", ErrInvalidCBOR, arg)
+}
+
+// --------------------------------------------------
+// Helpers
+// --------------------------------------------------
+
+// FormatRecordID formats a record id the same way SurrealDB does, for example user:bob or user:⟨bob smith⟩
+func FormatRecordID(table string, id any) string {
+	var b strings.Builder
+
+	b.WriteString(escapeRecordIDPart(table))
+	b.WriteByte(':')
+
+	switch id := id.(type) {
+	case string:
+		b.WriteString(escapeRecordIDPart(id))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, jsonNumber:
+		b.WriteString(fmt.Sprint(id))
+	default:
+		data, _ := json.Marshal(id)
+		b.Write(data)
+	}
+
+	return b.String()
+}
+
+func escapeRecordIDPart(part string) string {
+	if isSimpleIdentifier(part) {
+		return part
+	}
+
+	return "⟨" + strings.ReplaceAll(part, "⟩", `\⟩`) + "⟩"
+}
+
+// isSimpleIdentifier checks if the part of a record id can be written without escaping it
+// Only digits are escaped too, otherwise the id would be read as a number
+func isSimpleIdentifier(part string) bool {
+	if part == "" {
+		return false
+	}
+
+	digits := true
+	for _, r := range part {
+		switch {
+		case r >= '0' && r <= '9':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			digits = false
+		default:
+			return false
+		}
+	}
+
+	return !digits
+}
+
+// durationUnits are the units SurrealDB writes durations in, from largest to smallest
+var durationUnits = []struct {
+	name string
+	size time.Duration
+}{
+	{"y", 365 * 24 * time.Hour},
+	{"w", 7 * 24 * time.Hour},
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"µs", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// FormatDuration formats a duration the same way SurrealDB does, for example 1w2d3h or 1s500ms
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0ns"
+	}
+
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+
+	for _, unit := range durationUnits {
+		if count := d / unit.size; count > 0 {
+			b.WriteString(strconv.FormatInt(int64(count), 10))
+			b.WriteString(unit.name)
+			d -= count * unit.size
+		}
+	}
+
+	return b.String()
+}
+
+// ParseDuration parses a SurrealDB duration, for example 1w2d3h or 1s500ms
+// Go durations are accepted too, as every go unit is also a SurrealDB unit
+func ParseDuration(s string) (time.Duration, error) {
+	value := strings.TrimPrefix(s, "-")
+	if value == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var total time.Duration
+	for value != "" {
+		i := 0
+		for i < len(value) && (value[i] >= '0' && value[i] <= '9' || value[i] == '.') {
+			i++
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		count, err := strconv.ParseFloat(value[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		value = value[i:]
+
+		unit := durationUnitSize(value)
+		if unit.size == 0 {
+			return 0, fmt.Errorf("invalid unit in duration %q", s)
+		}
+		value = value[len(unit.name):]
+
+		total += time.Duration(count * float64(unit.size))
+	}
+
+	if strings.HasPrefix(s, "-") {
+		total = -total
+	}
+
+	return total, nil
+}
+
+// durationUnitSize finds the unit at the start of value, the two letter units are checked first
+func durationUnitSize(value string) struct {
+	name string
+	size time.Duration
+} {
+	for _, unit := range []struct {
+		name string
+		size time.Duration
+	}{{"ms", time.Millisecond}, {"µs", time.Microsecond}, {"us", time.Microsecond}, {"ns", time.Nanosecond}} {
+		if strings.HasPrefix(value, unit.name) {
+			return unit
+		}
+	}
+
+	for _, unit := range durationUnits {
+		if strings.HasPrefix(value, unit.name) {
+			return unit
+		}
+	}
+
+	return struct {
+		name string
+		size time.Duration
+	}{}
+}
+
+func secondsAndNanos(content any) (int64, int64, error) {
+	parts, ok := content.([]any)
+	if !ok || len(parts) > 2 {
+		return 0, 0, fmt.Errorf("%w: expected an array of seconds and nanoseconds", ErrInvalidCBOR)
+	}
+
+	values := [2]int64{}
+	for i, part := range parts {
+		switch part := part.(type) {
+		case uint64:
+			if part > math.MaxInt64 {
+				return 0, 0, fmt.Errorf("%w: seconds out of range", ErrInvalidCBOR)
+			}
+			values[i] = int64(part)
+		case int64:
+			values[i] = part
+		default:
+			return 0, 0, fmt.Errorf("%w: expected an array of seconds and nanoseconds", ErrInvalidCBOR)
+		}
+	}
+
+	return values[0], values[1], nil
+}
+
+func formatUUID(id []byte) string {
+	encoded := hex.EncodeToString(id)
+
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:32]
+}
+
+// negativeInteger formats the cbor negative integer -1-arg, which can be smaller than an int64
+func negativeInteger(arg uint64) string {
+	if arg < math.MaxInt64 {
+		return strconv.FormatInt(-1-int64(arg), 10)
+	}
+
+	n := new(big.Int).SetUint64(arg)
+	n.Add(n, big.NewInt(1))
+	n.Neg(n)
+
+	return n.String()
+}
+
+// jsonNumberPattern is the json number grammar, json.Valid isn't used as it also accepts leading zeros like 007
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func isJSONNumber(value string) bool {
+	return jsonNumberPattern.MatchString(value)
+}
+
+// halfToFloat32 converts an IEEE 754 half precision float
+func halfToFloat32(half uint16) float32 {
+	sign := uint32(half>>15) << 31
+	exponent := uint32(half>>10) & 0x1f
+	mantissa := uint32(half) & 0x3ff
+
+	switch exponent {
+	case 0:
+		// subnormal
+		value := float32(mantissa) / (1 << 24)
+		if sign != 0 {
+			value = -value
+		}
+		return value
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mantissa<<13)
+	}
+
+	return math.Float32frombits(sign | (exponent+112)<<23 | mantissa<<13)
+}
+
+func writeJSONValue(w *bytes.Buffer, value any) error {
+	switch value := value.(type) {
+	case nil:
+		w.WriteString("null")
+	case jsonNumber:
+		w.WriteString(string(value))
+	case string:
+		writeJSONString(w, []byte(value))
+	case float64:
+		// json has no NaN or infinity
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			w.WriteString("null")
+		} else {
+			w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+		}
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		w.Write(data)
+	}
+
+	return nil
+}
+
+// writeJSONString writes s as a json string, without escaping html like encoding/json does
+func writeJSONString(w *bytes.Buffer, s []byte) {
+	const hexDigits = "0123456789abcdef"
+
+	w.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+
+			w.Write(s[start:i])
+			switch b {
+			case '"', '\\':
+				w.WriteByte('\\')
+				w.WriteByte(b)
+			case '\n':
+				w.WriteString(`\n`)
+			case '\r':
+				w.WriteString(`\r`)
+			case '\t':
+				w.WriteString(`\t`)
+			default:
+				w.WriteString(`\u00`)
+				w.WriteByte(hexDigits[b>>4])
+				w.WriteByte(hexDigits[b&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			w.Write(s[start:i])
+			w.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	w.Write(s[start:])
+	w.WriteByte('"')
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// These are matched through errors.Is by the error types below, so the kind of error can be checked
+// without caring about its details, for example errors.Is(err, ErrPermission)
+var (
+	ErrConnection = errors.New("connection error")
+	ErrTimeout    = errors.New("request timed out")
+	ErrAuth       = errors.New("authentication error")
+	ErrPermission = errors.New("permission denied")
+	ErrParse      = errors.New("parse error")
+	ErrConflict   = errors.New("conflict")
+	ErrStatement  = errors.New("statement failed")
+)
+
+// ConnectionError is returned when the request couldn't be sent, or the connection was lost before its response arrived
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string        { return "connection error: " + e.Err.Error() }
+func (e *ConnectionError) Unwrap() error        { return e.Err }
+func (e *ConnectionError) Is(target error) bool { return target == ErrConnection }
+
+// TimeoutError is returned when the response didn't arrive before the deadline of the request
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string        { return "request timed out: " + e.Err.Error() }
+func (e *TimeoutError) Unwrap() error        { return e.Err }
+func (e *TimeoutError) Is(target error) bool { return target == ErrTimeout }
+func (e *TimeoutError) Timeout() bool        { return true }
+
+// AuthError is returned when the credentials are wrong, or the session isn't(or is no longer) authenticated
+type AuthError struct {
+	*RPCError
+}
+
+func (e *AuthError) Unwrap() error        { return e.RPCError }
+func (e *AuthError) Is(target error) bool { return target == ErrAuth }
+
+// PermissionError is returned when the session isn't allowed to do what was asked
+type PermissionError struct {
+	*RPCError
+}
+
+func (e *PermissionError) Unwrap() error        { return e.RPCError }
+func (e *PermissionError) Is(target error) bool { return target == ErrPermission }
+
+// ParseError is returned when the query couldn't be parsed, Line and Column are 0 when the message doesn't include them
+type ParseError struct {
+	*RPCError
+	Line   int
+	Column int
+}
+
+func (e *ParseError) Unwrap() error        { return e.RPCError }
+func (e *ParseError) Is(target error) bool { return target == ErrParse }
+
+// ConflictError is returned when a record already exists, or a transaction conflicted with another one
+type ConflictError struct {
+	*RPCError
+}
+
+func (e *ConflictError) Unwrap() error        { return e.RPCError }
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// StatementError is returned when a statement of a query failed
+type StatementError struct {
+	// Index of the statement in the query, starting at 0
+	Index   int
+	Status  string
+	Message string
+	// Excerpt is the part of the query the message points at, empty when the message doesn't include it
+	Excerpt string
+	// Err is what the message was classified as, like a *PermissionError, otherwise an *RPCError
+	Err error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %d failed: %s", e.Index, e.Message)
+}
+func (e *StatementError) Unwrap() error        { return e.Err }
+func (e *StatementError) Is(target error) bool { return target == ErrStatement }
+
+// NewStatementError creates the error for the statement at index, which failed with message
+func NewStatementError(index int, status string, message string) *StatementError {
+	return &StatementError{
+		Index:   index,
+		Status:  status,
+		Message: message,
+		Excerpt: excerpt(message),
+		Err:     ClassifyRPCError(&RPCError{Code: -32000, Message: message}),
+	}
+}
+
+// NewQueryStatementError is NewStatementError for a statement of query
+// When the message only has the position, the excerpt is the line of query it points at
+func NewQueryStatementError(index int, status string, message string, query string) *StatementError {
+	err := NewStatementError(index, status, message)
+	if err.Excerpt == "" {
+		line, _ := parsePosition(message)
+		err.Excerpt = queryLine(query, line)
+	}
+
+	return err
+}
+
+// --------------------------------------------------
+
+var (
+	// the messages are lowercased before they're matched
+	authMessages       = []string{"token has expired", "problem with authentication", "not authenticated", "authentication failed", "invalid credentials"}
+	permissionMessages = []string{"not enough permissions", "not allowed to", "permission denied", "don't have permission", "iam error"}
+	parseMessages      = []string{"parse error", "failed to parse"}
+	conflictMessages   = []string{"already exists", "conflict"}
+
+	// Parse error on line 1 at character 0 when parsing 'SELEC'
+	parsePositionPattern = regexp.MustCompile(`(?i)line (\d+) at character (\d+)`)
+	// the newer format points at the position: --> [1:7]
+	parseArrowPattern = regexp.MustCompile(`--> \[(\d+):(\d+)\]`)
+
+	// the query is quoted at the end of the older format
+	excerptQuotedPattern = regexp.MustCompile(`(?s)when parsing '(.*)'\s*$`)
+	// and shown under the position in the newer one:  1 | SELEC * FROM user
+	excerptSourcePattern = regexp.MustCompile(`(?m)^\s*\d+ \| (.*)$`)
+)
+
+// ClassifyError turns err into one of the typed errors above
+// Errors which are already typed, or don't match any of them, are returned as they are
+func ClassifyError(err error) error {
+	if err == nil || isClassified(err) {
+		return err
+	}
+
+	if rpcErr, ok := err.(*RPCError); ok {
+		return ClassifyRPCError(rpcErr)
+	}
+
+	var netErr net.Error
+	var closeErr *websocket.CloseError
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &TimeoutError{Err: err}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &TimeoutError{Err: err}
+	case errors.Is(err, ErrConnectionClosed), errors.Is(err, ErrProtocolNotSupported), errors.Is(err, websocket.ErrBadHandshake),
+		errors.As(err, &netErr), errors.As(err, &closeErr):
+		return &ConnectionError{Err: err}
+	}
+
+	return err
+}
+
+// ClassifyRPCError picks the type of an error returned by the database, from its code and message
+func ClassifyRPCError(err *RPCError) error {
+	message := strings.ToLower(err.Message)
+
+	switch {
+	case err.Code == 401 || containsAny(message, authMessages):
+		return &AuthError{RPCError: err}
+	case err.Code == 403 || containsAny(message, permissionMessages):
+		return &PermissionError{RPCError: err}
+	case err.Code == -32700 || containsAny(message, parseMessages):
+		parseErr := &ParseError{RPCError: err}
+		parseErr.Line, parseErr.Column = parsePosition(err.Message)
+		return parseErr
+	case err.Code == 409 || containsAny(message, conflictMessages):
+		return &ConflictError{RPCError: err}
+	}
+
+	return err
+}
+
+func isClassified(err error) bool {
+	for _, kind := range []error{ErrConnection, ErrTimeout, ErrAuth, ErrPermission, ErrParse, ErrConflict, ErrStatement} {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parsePosition(message string) (int, int) {
+	match := parsePositionPattern.FindStringSubmatch(message)
+	if match == nil {
+		match = parseArrowPattern.FindStringSubmatch(message)
+	}
+	if match == nil {
+		return 0, 0
+	}
+
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+
+	return line, column
+}
+
+func excerpt(message string) string {
+	if match := excerptQuotedPattern.FindStringSubmatch(message); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	if match := excerptSourcePattern.FindStringSubmatch(message); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+
+	return ""
+}
+
+// queryLine returns line(starting at 1) of query, or an empty string when it doesn't have that line
+func queryLine(query string, line int) string {
+	lines := strings.Split(query, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	return strings.TrimSpace(lines[line-1])
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+
+	return false
+}
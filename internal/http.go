@@ -0,0 +1,543 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+var (
+	// ErrUnsupportedMethod is returned when the rpc method has no equivalent http endpoint
+	ErrUnsupportedMethod = errors.New("this method is not supported by the http transport")
+	// ErrInvalidParams is returned when the params for a method are not what we expect
+	ErrInvalidParams = errors.New("invalid params for this method")
+
+	// variable names are written directly into the query, so we make sure they can't be used for injection
+	validVariableName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// HTTP talks to the database using the http endpoints, instead of holding a websocket open
+// The session state(ns/db, auth and let variables) is kept here and sent with every request
+type HTTP struct {
+	url     string
+	client  *http.Client
+	timeout time.Duration
+	headers http.Header // sent with every request
+	codec   Config.Codec
+	state   connectionState
+
+	// ctx is cancelled by Close, which fails the requests in-flight and every request after it
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock      sync.RWMutex
+	namespace string
+	database  string
+	token     string
+	username  string
+	password  string
+	vars      map[string]any
+}
+
+// statementResult is a single statement result from the /sql and /key endpoints
+type statementResult struct {
+	Time   string          `json:"time"`
+	Status string          `json:"status"`
+	Detail string          `json:"detail,omitempty"`
+	Result json.RawMessage `json:"result"`
+}
+
+// httpError is the body of a non 2xx response
+type httpError struct {
+	Code        int64  `json:"code"`
+	Details     string `json:"details"`
+	Description string `json:"description"`
+	Information string `json:"information"`
+}
+
+func NewHTTP(config *Config.DbConfig) (*HTTP, error) {
+	base, err := url.Parse(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("http transport requires a http(s) url, got: %s", config.Url)
+	}
+
+	if config.Protocol == Config.ProtocolCBOR {
+		return nil, fmt.Errorf("%w: the http transport only supports json", ErrProtocolNotSupported)
+	}
+
+	// People will likely re-use their websocket url, so we'll just take it off
+	base.Path = strings.TrimSuffix(strings.TrimSuffix(base.Path, "/"), "/rpc")
+
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	httpTransport.TLSClientConfig = config.TLS
+	if config.Dial != nil && config.Dial.Proxy != nil {
+		httpTransport.Proxy = config.Dial.Proxy
+	}
+	if config.Dial != nil && config.Dial.DisableCompression {
+		httpTransport.DisableCompression = true
+	}
+
+	transport := &HTTP{
+		url:    strings.TrimSuffix(base.String(), "/"),
+		client: &http.Client{Transport: httpTransport},
+		codec:  config.CodecOrDefault(),
+		vars:   make(map[string]any),
+	}
+	transport.ctx, transport.cancel = context.WithCancel(context.Background())
+
+	if config.Timeouts != nil {
+		transport.timeout = config.Timeouts.Timeout
+	}
+	if config.Dial != nil {
+		transport.headers = config.Dial.Headers
+	}
+
+	// there's no connection to keep open, so we're connected until we're closed
+	transport.state.observer = config.Observer
+	transport.state.set(Config.ConnectionConnected)
+
+	return transport, nil
+}
+
+// --------------------------------------------------
+// Public methods
+// --------------------------------------------------
+
+// Send maps the rpc method to its http endpoint and returns the response as if it came from the rpc endpoint
+func (h *HTTP) Send(ctx context.Context, id string, method string, params []any) (*RPCRawResponse, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if h.ctx.Err() != nil {
+		return nil, ErrConnectionClosed
+	}
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	ctx, cancel := h.requestContext(ctx)
+	defer cancel()
+
+	result, err := h.dispatch(ctx, method, params)
+	if err != nil {
+		if h.ctx.Err() != nil {
+			return nil, ErrConnectionClosed
+		}
+		return nil, err
+	}
+
+	return h.respond(id, result)
+}
+
+// Close fails the requests in-flight, and any sent afterwards, with ErrConnectionClosed
+func (h *HTTP) Close() error {
+	h.cancel()
+	h.state.set(Config.ConnectionClosed)
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+// --------------------------------------------------
+// Private methods
+// --------------------------------------------------
+
+// requestContext returns a context which is also cancelled when the transport is closed
+func (h *HTTP) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-h.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+func (h *HTTP) dispatch(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	switch method {
+	case "use":
+		ns, db, err := stringParams(params)
+		if err != nil {
+			return nil, err
+		}
+		h.lock.Lock()
+		h.namespace, h.database = ns, db
+		h.lock.Unlock()
+		return nil, nil
+
+	case "signin", "signup":
+		if len(params) != 1 {
+			return nil, ErrInvalidParams
+		}
+		return h.signin(ctx, method, params[0])
+
+	case "authenticate":
+		if len(params) != 1 {
+			return nil, ErrInvalidParams
+		}
+		token, ok := params[0].(string)
+		if !ok {
+			return nil, ErrInvalidParams
+		}
+		h.lock.Lock()
+		h.token, h.username, h.password = token, "", ""
+		h.lock.Unlock()
+		return nil, nil
+
+	case "invalidate":
+		h.lock.Lock()
+		h.token, h.username, h.password = "", "", ""
+		h.lock.Unlock()
+		return nil, nil
+
+	case "let":
+		if len(params) != 2 {
+			return nil, ErrInvalidParams
+		}
+		key, ok := params[0].(string)
+		if !ok || !validVariableName.MatchString(key) {
+			return nil, ErrInvalidParams
+		}
+		h.lock.Lock()
+		h.vars[key] = params[1]
+		h.lock.Unlock()
+		return nil, nil
+
+	case "unset":
+		if len(params) != 1 {
+			return nil, ErrInvalidParams
+		}
+		key, _ := params[0].(string)
+		h.lock.Lock()
+		delete(h.vars, key)
+		h.lock.Unlock()
+		return nil, nil
+
+	case "query":
+		if len(params) == 0 {
+			return nil, ErrInvalidParams
+		}
+		sql, ok := params[0].(string)
+		if !ok {
+			return nil, ErrInvalidParams
+		}
+		var vars any
+		if len(params) > 1 {
+			vars = params[1]
+		}
+		return h.query(ctx, sql, vars)
+
+	case "info":
+		results, err := h.query(ctx, "SELECT * FROM $auth", nil)
+		if err != nil {
+			return nil, err
+		}
+		return h.firstStatement(results)
+
+	case "select", "create", "update", "change", "delete":
+		return h.key(ctx, method, params)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+}
+
+// signin handles both signin and signup, which share the same response
+func (h *HTTP) signin(ctx context.Context, method string, vars any) (json.RawMessage, error) {
+	body, err := h.codec.Marshal(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := h.request(ctx, http.MethodPost, "/"+method, bytes.NewReader(body), "application/json", false)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := h.codec.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	h.lock.Lock()
+	h.token, h.username, h.password = response.Token, "", ""
+	// Older versions don't give a token for root users, so we fall back to basic auth with the credentials
+	if response.Token == "" {
+		var credentials struct {
+			User string `json:"user"`
+			Pass string `json:"pass"`
+		}
+		if h.codec.Unmarshal(body, &credentials) == nil {
+			h.username, h.password = credentials.User, credentials.Pass
+		}
+	}
+	h.lock.Unlock()
+
+	return h.codec.Marshal(response.Token)
+}
+
+// query runs the sql through /sql, the variables are defined using LET statements in front of the query
+// their results are then removed, so the response lines up with the statements which were passed in
+func (h *HTTP) query(ctx context.Context, sql string, vars any) (json.RawMessage, error) {
+	variables := map[string]any{}
+
+	h.lock.RLock()
+	for key, value := range h.vars {
+		variables[key] = value
+	}
+	h.lock.RUnlock()
+
+	if vars != nil {
+		data, err := h.codec.Marshal(vars)
+		if err != nil {
+			return nil, err
+		}
+		var queryVars map[string]any
+		if err := h.codec.Unmarshal(data, &queryVars); err != nil {
+			return nil, ErrInvalidParams
+		}
+		for key, value := range queryVars {
+			variables[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		if !validVariableName.MatchString(key) {
+			return nil, fmt.Errorf("%w: invalid variable name %q", ErrInvalidParams, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var statements strings.Builder
+	for _, key := range keys {
+		value, err := h.codec.Marshal(variables[key])
+		if err != nil {
+			return nil, err
+		}
+		statements.WriteString("LET $" + key + " = " + string(value) + ";\n")
+	}
+	statements.WriteString(sql)
+
+	req, err := h.request(ctx, http.MethodPost, "/sql", strings.NewReader(statements.String()), "text/plain", true)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return data, nil
+	}
+
+	var results []json.RawMessage
+	if err := h.codec.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	if len(results) < len(keys) {
+		return nil, fmt.Errorf("expected at least %d statement results, got %d", len(keys), len(results))
+	}
+
+	return h.codec.Marshal(results[len(keys):])
+}
+
+// key handles the crud methods using the /key/:table/:id endpoints
+func (h *HTTP) key(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	if len(params) == 0 {
+		return nil, ErrInvalidParams
+	}
+	what, ok := params[0].(string)
+	if !ok || what == "" {
+		return nil, ErrInvalidParams
+	}
+
+	path := "/key/"
+	if table, id, found := strings.Cut(what, ":"); found {
+		path += url.PathEscape(table) + "/" + url.PathEscape(strings.Trim(id, "⟨⟩`"))
+	} else {
+		path += url.PathEscape(what)
+	}
+
+	httpMethod := map[string]string{
+		"select": http.MethodGet,
+		"create": http.MethodPost,
+		"update": http.MethodPut,
+		"change": http.MethodPatch,
+		"delete": http.MethodDelete,
+	}[method]
+
+	var body io.Reader
+	if (method == "create" || method == "update" || method == "change") && len(params) > 1 {
+		data, err := h.codec.Marshal(params[1])
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := h.request(ctx, httpMethod, path, body, "application/json", true)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := h.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.firstStatement(data)
+}
+
+func (h *HTTP) request(ctx context.Context, method string, path string, body io.Reader, contentType string, authenticated bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, h.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range h.headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	if h.namespace != "" {
+		req.Header.Set("NS", h.namespace)
+	}
+	if h.database != "" {
+		req.Header.Set("DB", h.database)
+	}
+
+	if authenticated {
+		if h.token != "" {
+			req.Header.Set("Authorization", "Bearer "+h.token)
+		} else if h.username != "" {
+			req.SetBasicAuth(h.username, h.password)
+		}
+	}
+
+	return req, nil
+}
+
+func (h *HTTP) do(req *http.Request) ([]byte, error) {
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var body httpError
+		if err := h.codec.Unmarshal(data, &body); err != nil || (body.Information == "" && body.Description == "") {
+			return nil, ClassifyRPCError(&RPCError{Code: int64(res.StatusCode), Message: strings.TrimSpace(string(data))})
+		}
+
+		message := body.Information
+		if message == "" {
+			message = body.Description
+		}
+		return nil, ClassifyRPCError(&RPCError{Code: body.Code, Message: message})
+	}
+
+	return data, nil
+}
+
+// respond builds the same response the rpc endpoint would've given us, so the resolvers can be shared
+func (h *HTTP) respond(id string, result json.RawMessage) (*RPCRawResponse, error) {
+	response := struct {
+		ID     string          `json:"id"`
+		Result json.RawMessage `json:"result"`
+	}{ID: id, Result: result}
+
+	if len(result) == 0 {
+		response.Result = json.RawMessage("null")
+	}
+
+	data, err := h.codec.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRPCRawResponse(data, h.codec), nil
+}
+
+// firstStatement pulls the result out of the first statement, in the same shape as the rpc response
+func (h *HTTP) firstStatement(data []byte) (json.RawMessage, error) {
+	var results []statementResult
+	if err := h.codec.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return json.RawMessage("[]"), nil
+	}
+
+	if results[0].Status != "OK" {
+		message := results[0].Detail
+		if message == "" {
+			_ = h.codec.Unmarshal(results[0].Result, &message)
+		}
+		return nil, NewStatementError(0, results[0].Status, message)
+	}
+
+	return results[0].Result, nil
+}
+
+func stringParams(params []any) (string, string, error) {
+	if len(params) != 2 {
+		return "", "", ErrInvalidParams
+	}
+
+	first, ok := params[0].(string)
+	if !ok {
+		return "", "", ErrInvalidParams
+	}
+	second, ok := params[1].(string)
+	if !ok {
+		return "", "", ErrInvalidParams
+	}
+
+	return first, second, nil
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var liveStreamId uint64
+
+// LiveStream receives the notifications for a single live query
+// Notifications are queued without a limit, so a slow reader never blocks the main loop
+type LiveStream struct {
+	ws  *WS
+	key string // used to register our when() listener, so it can be removed again
+
+	// the request which started the live query, so it can be restarted after reconnecting
+	// it's empty for streams created through WS.When, those are closed when the connection is lost
+	method string
+	params []any
+	// response is the response to the request which started the live query
+	response *RPCRawResponse
+
+	lock  sync.Mutex
+	id    string
+	queue []*RPCRawResponse
+
+	signal  chan struct{}
+	out     chan *RPCRawResponse
+	done    chan struct{}
+	closed  sync.Once
+	onClose []func()
+}
+
+// Response returns the response to the request which started the live query
+func (stream *LiveStream) Response() *RPCRawResponse {
+	return stream.response
+}
+
+func newLiveStream(ws *WS) *LiveStream {
+	stream := &LiveStream{
+		ws:     ws,
+		key:    "live-" + strconv.FormatUint(atomic.AddUint64(&liveStreamId, 1), 16),
+		signal: make(chan struct{}, 1),
+		out:    make(chan *RPCRawResponse),
+		done:   make(chan struct{}),
+	}
+
+	go stream.pump()
+
+	return stream
+}
+
+// Id returns the id of the live query on the server
+// The id changes when the live query is restarted after reconnecting
+func (stream *LiveStream) Id() string {
+	stream.lock.Lock()
+	defer stream.lock.Unlock()
+
+	return stream.id
+}
+
+// Notifications receives every notification for the live query, it's closed along with the stream
+func (stream *LiveStream) Notifications() <-chan *RPCRawResponse {
+	return stream.out
+}
+
+// Send sends a request over the connection the live query was started on, which is the only one that can kill it
+func (stream *LiveStream) Send(ctx context.Context, id string, method string, params []any) (*RPCRawResponse, error) {
+	return stream.ws.Send(ctx, id, method, params)
+}
+
+// Close stops listening for notifications, without killing the live query on the server
+func (stream *LiveStream) Close() {
+	stream.closed.Do(func() {
+		stream.ws.removeWhen(stream.Id(), stream.key)
+		close(stream.done)
+
+		stream.lock.Lock()
+		onClose := stream.onClose
+		stream.lock.Unlock()
+
+		for _, fn := range onClose {
+			fn()
+		}
+	})
+}
+
+// OnClose registers fn to be called once the stream has been closed, it's called straight away when it already is
+func (stream *LiveStream) OnClose(fn func()) {
+	stream.lock.Lock()
+	select {
+	case <-stream.done:
+		// Close has already taken the callbacks, or is about to, without this one
+		stream.lock.Unlock()
+		fn()
+		return
+	default:
+	}
+	stream.onClose = append(stream.onClose, fn)
+	stream.lock.Unlock()
+}
+
+// Done is closed once the stream has been closed
+func (stream *LiveStream) Done() <-chan struct{} {
+	return stream.done
+}
+
+// push queues a notification, this is called from the main loop so it must never block
+func (stream *LiveStream) push(res *RPCRawResponse) {
+	stream.lock.Lock()
+	stream.queue = append(stream.queue, res)
+	stream.lock.Unlock()
+
+	select {
+	case stream.signal <- struct{}{}:
+	default:
+	}
+}
+
+// pump moves the queued notifications to the out channel, in the order they arrived
+func (stream *LiveStream) pump() {
+	defer close(stream.out)
+
+	for {
+		stream.lock.Lock()
+		var next *RPCRawResponse
+		if len(stream.queue) > 0 {
+			next = stream.queue[0]
+			stream.queue[0] = nil
+			stream.queue = stream.queue[1:]
+		}
+		stream.lock.Unlock()
+
+		if next == nil {
+			select {
+			case <-stream.done:
+				return
+			case <-stream.signal:
+			}
+			continue
+		}
+
+		select {
+		case <-stream.done:
+			return
+		case stream.out <- next:
+		}
+	}
+}
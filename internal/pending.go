@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrConnectionClosed is returned for every outstanding request when the connection drops or is closed
+	ErrConnectionClosed = errors.New("the connection to the database was closed")
+)
+
+type responseValue struct {
+	Value  *RPCRawResponse
+	Method string
+	Err    error
+}
+
+// pendingRequest is a request which has been queued/sent, and is waiting for its response
+type pendingRequest struct {
+	method string
+	// called from the main loop with the response, before any other message is processed
+	onResolve func(*RPCRawResponse)
+	// buffered, so resolving never blocks on a caller which stopped waiting
+	response chan responseValue
+}
+
+// pendingRequests holds every request waiting for a response, keyed by the rpc id
+// Entries are always removed once they're resolved, failed or the caller stops waiting
+type pendingRequests struct {
+	lock     sync.Mutex
+	requests map[string]*pendingRequest
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{requests: make(map[string]*pendingRequest)}
+}
+
+// add registers a new request, the returned channel receives exactly one value
+func (p *pendingRequests) add(id, method string, onResolve ...func(*RPCRawResponse)) <-chan responseValue {
+	request := &pendingRequest{
+		method:   method,
+		response: make(chan responseValue, 1),
+	}
+	if len(onResolve) > 0 {
+		request.onResolve = onResolve[0]
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.requests[id] = request
+
+	return request.response
+}
+
+// has checks if anyone is still waiting for the response of id
+func (p *pendingRequests) has(id string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	_, ok := p.requests[id]
+	return ok
+}
+
+// resolve delivers the response to the request waiting for it
+// Returns false when no one was waiting for this response
+func (p *pendingRequests) resolve(res *RPCRawResponse) bool {
+	request := p.take(res.Id())
+	if request == nil {
+		return false
+	}
+
+	if request.onResolve != nil {
+		request.onResolve(res)
+	}
+
+	request.response <- responseValue{
+		Value:  res,
+		Method: request.method,
+		Err:    res.Error(),
+	}
+
+	return true
+}
+
+// fail resolves a single request with an error
+func (p *pendingRequests) fail(id string, err error) {
+	request := p.take(id)
+	if request == nil {
+		return
+	}
+
+	request.response <- responseValue{Method: request.method, Err: err}
+}
+
+// remove forgets about a request, without resolving it
+func (p *pendingRequests) remove(id string) {
+	p.take(id)
+}
+
+// failAll resolves every outstanding request with err
+func (p *pendingRequests) failAll(err error) {
+	p.lock.Lock()
+	requests := p.requests
+	p.requests = make(map[string]*pendingRequest)
+	p.lock.Unlock()
+
+	for _, request := range requests {
+		request.response <- responseValue{Method: request.method, Err: err}
+	}
+}
+
+// len returns the amount of outstanding requests
+func (p *pendingRequests) len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return len(p.requests)
+}
+
+func (p *pendingRequests) take(id string) *pendingRequest {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	request, ok := p.requests[id]
+	if !ok {
+		return nil
+	}
+	delete(p.requests, id)
+
+	return request
+}
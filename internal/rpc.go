@@ -1,9 +1,11 @@
 package internal
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
 
 	"github.com/buger/jsonparser"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
 )
 
 // RPCError represents a JSON-RPC error
@@ -55,27 +57,25 @@ type RPCNotification struct {
 type RPCRawResponse struct {
 	// Holds the raw socket response data
 	rawData []byte
+	// The pooled buffer rawData was read into, see Release
+	buffer *bytes.Buffer
+	// The codec of the connection the response came from, used to decode the error and result
+	codec Config.Codec
 
 	// The method used for the RPCRequest, query, create, update etc
 	rpcMethod string
 
-	// Set to true when we've pulled the "result" data out of the response
-	hasDecodedRpcResult bool
-	// Holds the data from the "result" field in the response
+	// Holds the data from the "result" field in the response, this is a slice of rawData rather than a copy
 	rpcResult []byte
 	// Holds the type of the "result" for future reference in QueryResolver
 	rpcResultDataType jsonparser.ValueType
 
-	// Set to true when we've decoded the rpc message id
-	hasDecodedRpcId bool
 	// The id string, once we've decoded it from the rpc response
 	id string
 
 	// This is set to true when we do 100% have an error, we can't just check for error != nil
 	// because we need to check for the error field in the response, so by default, error will be nil
 	hasRpcError bool
-	// This is set to true, when we've done our look-up for the error field
-	hasDecodedRpcError bool
 	// When we've done our checks and decoded the error, we set it here, if it exists
 	rpcError *RPCError
 
@@ -83,92 +83,103 @@ type RPCRawResponse struct {
 	internalProcessingError error
 }
 
+// rpcResponsePaths are the fields resolve looks for, in the order of the index passed to its callback
+var rpcResponsePaths = [][]string{{"id"}, {"error"}, {"result"}}
+
+// CreateRPCRawResponse creates a response which is decoded using the default codec
 func CreateRPCRawResponse(data []byte) *RPCRawResponse {
+	return newRPCRawResponse(data, Config.GoccyCodec{})
+}
+
+func newRPCRawResponse(data []byte, codec Config.Codec) *RPCRawResponse {
 	response := &RPCRawResponse{
 		rawData: data,
+		codec:   codec,
 	}
 
-	response.resolveId()
-	response.resolveError()
-
-	// If we didn't hit an error, then we'll pull the "result" data out of the
-	// response and store it for the end user to decode how they wish!
-	if !response.HasError() {
-		response.resolveResult()
-	}
+	response.resolve()
 
 	return response
 }
 
-// resolveError will try to do a cheap decoding of the response to check if we have an rpc error
-// this will just prevent unmarshalling and re-marshalling multiple times.
-func (res *RPCRawResponse) resolveError() {
-	if res.hasDecodedRpcError {
+// resolve pulls the id, error and result out of the response in a single pass over the data
+// The "result" isn't decoded, it's left for the end user to decode how they wish!
+func (res *RPCRawResponse) resolve() {
+	jsonparser.EachKey(res.rawData, func(idx int, value []byte, dataType jsonparser.ValueType, err error) {
+		if err != nil {
+			res.internalProcessingError = err
+			return
+		}
+
+		switch idx {
+		case 0:
+			res.resolveId(value, dataType)
+		case 1:
+			res.resolveError(value, dataType)
+		case 2:
+			res.rpcResult = value
+			res.rpcResultDataType = dataType
+		}
+	}, rpcResponsePaths...)
+
+	if res.hasRpcError {
+		res.rpcResult = nil
+		res.rpcResultDataType = jsonparser.NotExist
 		return
 	}
 
-	// After some benchmarks:
-	// BenchmarkDecodeRpcError-10   8340408	   142.3 ns/op
-	// Time wise, it's cheap to look up, but then takes the same time if we just json.Unmarshal once we know it's there.
-	errorValue, dataType, _, err := jsonparser.Get(res.rawData, "error")
-	if err != nil && dataType != jsonparser.NotExist {
-		res.internalProcessingError = err
-		return
+	if res.rpcResultDataType == jsonparser.NotExist && res.internalProcessingError == nil {
+		res.internalProcessingError = jsonparser.KeyPathNotFoundError
 	}
-	// There's no error field in the response
-	if dataType == jsonparser.NotExist {
-		res.hasRpcError = false
-		res.hasDecodedRpcError = true
+}
+
+// resolveError decodes the error field, once we know the response has one
+func (res *RPCRawResponse) resolveError(value []byte, dataType jsonparser.ValueType) {
+	if dataType == jsonparser.Null {
 		return
 	}
 
-	err = json.Unmarshal(errorValue, &res.rpcError)
-	if err != nil {
+	if err := res.codec.Unmarshal(value, &res.rpcError); err != nil {
 		res.internalProcessingError = err
 		return
 	}
 
 	res.hasRpcError = true
-	res.hasDecodedRpcError = true
-
-	return
 }
 
-// resolveId this will try to do a cheap decoding of the response to check if we have an rpc id
-func (res *RPCRawResponse) resolveId() {
-	if res.hasDecodedRpcId {
+// resolveId decodes the id field, live query notifications are pushed by the server without one
+func (res *RPCRawResponse) resolveId(value []byte, dataType jsonparser.ValueType) {
+	if dataType == jsonparser.Null {
 		return
 	}
 
-	id, err := jsonparser.GetString(res.rawData, "id")
+	id, err := jsonparser.ParseString(value)
 	if err != nil {
 		res.internalProcessingError = err
 		return
 	}
 
 	res.id = id
-	res.hasDecodedRpcId = true
 }
 
-func (res *RPCRawResponse) resolveResult() {
-	if res.hasDecodedRpcResult {
+// Release hands the buffer the response was read into back to the pool, to be reused for another response
+// Neither the response nor anything taken from RawData/Result may be used afterwards
+func (res *RPCRawResponse) Release() {
+	if res.buffer == nil {
 		return
 	}
 
-	resultData, dataType, _, err := jsonparser.Get(res.rawData, "result")
-	if err != nil {
-		res.internalProcessingError = err
-		return
-	}
+	buf := res.buffer
+	res.buffer = nil
+	res.rawData = nil
+	res.rpcResult = nil
 
-	res.rpcResultDataType = dataType
-	res.rpcResult = resultData
-	res.hasDecodedRpcResult = true
+	putBuffer(buf)
 }
 
 // HasError Check if we have an error set
 func (res *RPCRawResponse) HasError() bool {
-	return res.Error() != nil
+	return (res.hasRpcError && res.rpcError != nil) || res.HasInternalError()
 }
 
 // HasInternalError Check if we have an internal error set
@@ -177,9 +188,10 @@ func (res *RPCRawResponse) HasInternalError() bool {
 }
 
 // Error returns the error we discovered in the rpc response, or the error we encountered while decoding
+// Errors from the database are typed by ClassifyRPCError, they all unwrap to the *RPCError
 func (res *RPCRawResponse) Error() error {
 	if res.hasRpcError && res.rpcError != nil {
-		return res.rpcError
+		return ClassifyRPCError(res.rpcError)
 	}
 
 	if res.HasInternalError() {
@@ -197,11 +209,128 @@ func (res *RPCRawResponse) RawData() []byte {
 	return res.rawData
 }
 
+// IsNotification checks if this is a live query notification, rather than a response to a request
+func (res *RPCRawResponse) IsNotification() bool {
+	if res.id != "" || res.rpcResultDataType != jsonparser.Object {
+		return false
+	}
+
+	_, dataType, _, _ := jsonparser.Get(res.rpcResult, "action")
+	return dataType == jsonparser.String
+}
+
+// LiveActionResynced is the action of the notification we send ourselves, once a live query has been
+// restarted after reconnecting. Any changes made whilst we were disconnected have been missed
+const LiveActionResynced = "RESYNCED"
+
+// newResyncedNotification creates the notification which marks a live query as restarted
+func newResyncedNotification(liveId string, codec Config.Codec) *RPCRawResponse {
+	data, _ := codec.Marshal(map[string]any{
+		"result": map[string]any{"id": liveId, "action": LiveActionResynced},
+	})
+
+	return newRPCRawResponse(data, codec)
+}
+
+// LiveNotificationData is the content of a live query notification
+type LiveNotificationData struct {
+	// The id of the live query this notification belongs to
+	Id string
+	// CREATE, UPDATE or DELETE
+	Action string
+	// The id of the record which changed, when the server tells us
+	Record string
+	// The record, or a json patch when using LIVE SELECT DIFF
+	Result []byte
+	Type   jsonparser.ValueType
+}
+
+// LiveNotification pulls the notification data out of the "result"
+// {"result":{"id":"0189d6e3-8eac-703a-9a48-d9faa78b44b9","action":"CREATE","result":{"id":"user:bob"}}}
+func (res *RPCRawResponse) LiveNotification() (*LiveNotificationData, error) {
+	if !res.IsNotification() {
+		return nil, errors.New("the response is not a live query notification")
+	}
+
+	notification := &LiveNotificationData{}
+
+	var err error
+	if notification.Id, err = jsonparser.GetString(res.rpcResult, "id"); err != nil {
+		return nil, err
+	}
+	if notification.Action, err = jsonparser.GetString(res.rpcResult, "action"); err != nil {
+		return nil, err
+	}
+	notification.Record, _ = jsonparser.GetString(res.rpcResult, "record")
+
+	notification.Result, notification.Type, _, err = jsonparser.Get(res.rpcResult, "result")
+	if err != nil && notification.Type != jsonparser.NotExist {
+		return nil, err
+	}
+
+	return notification, nil
+}
+
+// LiveQueryId pulls the id of a live query out of the response to a "live" request,
+// or the response to a "query" request running LIVE SELECT
+func (res *RPCRawResponse) LiveQueryId() (string, error) {
+	if res.HasError() {
+		return "", res.Error()
+	}
+
+	switch res.rpcResultDataType {
+	case jsonparser.String:
+		return jsonparser.ParseString(res.rpcResult)
+
+	case jsonparser.Array:
+		status, _ := jsonparser.GetString(res.rpcResult, "[0]", "status")
+		if status != "" && status != "OK" {
+			message, _ := jsonparser.GetString(res.rpcResult, "[0]", "detail")
+			if message == "" {
+				message, _ = jsonparser.GetString(res.rpcResult, "[0]", "result")
+			}
+			return "", NewStatementError(0, status, message)
+		}
+
+		return jsonparser.GetString(res.rpcResult, "[0]", "result")
+	}
+
+	return "", errors.New("the response does not contain a live query id")
+}
+
+// ResultString returns the result when it's a string, like the token returned by signin
+func (res *RPCRawResponse) ResultString() (string, bool) {
+	if res.rpcResultDataType != jsonparser.String {
+		return "", false
+	}
+
+	value, err := jsonparser.ParseString(res.rpcResult)
+	if err != nil {
+		return "", false
+	}
+
+	return value, true
+}
+
 type RpcResultData struct {
 	Result []byte
 	Type   jsonparser.ValueType
 }
 
+// DecodeResult decodes the "result" into v, using the codec of the connection the response came from
+func (res *RPCRawResponse) DecodeResult(v any) error {
+	if res.HasError() {
+		return res.Error()
+	}
+
+	return res.codec.Unmarshal(res.rpcResult, v)
+}
+
+// Codec returns the codec of the connection the response came from
+func (res *RPCRawResponse) Codec() Config.Codec {
+	return res.codec
+}
+
 func (res *RPCRawResponse) Result() *RpcResultData {
 	return &RpcResultData{
 		Result: res.rpcResult,
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"sync"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+// connectionState tracks the state of a transport's connection, reporting every change to the observer
+type connectionState struct {
+	// lock is held whilst the observer is called, so it sees the changes in order
+	lock     sync.Mutex
+	state    Config.ConnectionState
+	observer Config.Observer
+}
+
+// set moves to the new state, nothing happens once closed
+func (s *connectionState) set(to Config.ConnectionState) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	from := s.state
+	if from == to || from == Config.ConnectionClosed {
+		return
+	}
+	s.state = to
+
+	if s.observer != nil {
+		s.observer.OnConnectionState(from, to)
+	}
+}
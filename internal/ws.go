@@ -1,60 +1,183 @@
 package internal
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
-	"io"
-	"log"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
 )
 
-type WS struct {
-	ws   *websocket.Conn        // websocket connection
-	send chan<- *RPCRequest     // sender channel
-	recv <-chan *RPCRawResponse // receive channel
+const (
+	// replayTimeout is used when replaying the session and no timeout has been configured
+	replayTimeout = 10 * time.Second
+	// defaultQueueSize is used when the send queue size hasn't been configured
+	defaultQueueSize = 256
+)
+
+var (
+	// ErrQueueFull is returned when the send queue is full and it's configured to fail fast
+	ErrQueueFull = errors.New("the send queue is full")
+	// ErrProtocolNotSupported is returned when the server doesn't accept the configured protocol
+	ErrProtocolNotSupported = errors.New("the server does not support the requested protocol")
+
+	errClosed       = errors.New("websocket has been closed")
+	errReconnecting = errors.New("websocket is already reconnecting")
+)
 
-	timeout time.Duration
+type WS struct {
+	url  string
+	conn *connection          // the currently active connection
+	send chan *RPCRequest     // bounded sender queue, kept across reconnects so requests keep their order
+	recv chan *RPCRawResponse // receive channel, kept across reconnects
+
+	timeout   time.Duration
+	reconnect *Config.DbReconnectConfig
+	dialer    *websocket.Dialer // every connection has its own, so settings don't leak between them
+	headers   http.Header
+	readLimit int64
+	keepalive *Config.DbKeepaliveConfig
+	failFast  bool // fail with ErrQueueFull, instead of waiting for space in the send queue
+	codec     Config.Codec
+	binary    bool // messages are sent and received as cbor, see Config.ProtocolCBOR
+	logger    Config.Logger
+	state     connectionState
 
 	emit struct {
-		// TODO: use the lock less, through smaller locks (separate once/when locks ?)
+		// TODO: use the lock less, through smaller locks
 		// or ideally by removing locks altogether
 		lock sync.Mutex // pause threads to avoid conflicts
 
-		// do the callbacks really need to be a list ?
-		once map[any][]func(error, any) // once listeners
-		when map[any][]func(error, any) // when listeners
+		when    map[string]map[string]func(*RPCRawResponse) // live query listeners, by live query id
+		streams map[string]*LiveStream                      // open live streams, by stream key
+	}
+
+	// requests which are waiting for their response
+	pending *pendingRequests
+
+	// session holds the requests which modify the state of the connection(signin, use, let etc)
+	// these are replayed in order, whenever we re-establish the connection
+	session struct {
+		lock     sync.Mutex
+		keys     []string
+		requests map[string]*RPCRequest
 	}
-	ctx context.Context
+
+	lock     sync.Mutex // guards conn and closed
+	closed   bool
+	replayId uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewWebsocket(url string, timeout ...*Config.DbTimeoutConfig) (*WS, error) {
-	dialer := websocket.DefaultDialer
-	dialer.EnableCompression = true
+// connection holds a single websocket connection and the context of its read/write loops
+type connection struct {
+	ws      *websocket.Conn
+	ctx     context.Context
+	cancel  context.CancelFunc
+	dropped int32
+}
 
-	// establish connection
-	so, _, err := dialer.Dial(url, nil)
-	if err != nil {
-		return nil, err
+// drop stops the loops and closes the socket, it returns false if the connection was already dropped
+func (conn *connection) drop() bool {
+	if !atomic.CompareAndSwapInt32(&conn.dropped, 0, 1) {
+		return false
+	}
+
+	conn.cancel()
+	conn.ws.Close()
+
+	return true
+}
+
+// newDialer creates the dialer for a connection from the config
+func newDialer(config *Config.DbConfig) *websocket.Dialer {
+	dialer := &websocket.Dialer{
+		Proxy:             http.ProxyFromEnvironment,
+		HandshakeTimeout:  45 * time.Second,
+		EnableCompression: true,
+		TLSClientConfig:   config.TLS,
+	}
+
+	if config.Protocol == Config.ProtocolCBOR {
+		dialer.Subprotocols = []string{string(Config.ProtocolCBOR)}
+	}
+
+	if dial := config.Dial; dial != nil {
+		if dial.Proxy != nil {
+			dialer.Proxy = dial.Proxy
+		}
+		if dial.HandshakeTimeout > 0 {
+			dialer.HandshakeTimeout = dial.HandshakeTimeout
+		}
+		dialer.EnableCompression = !dial.DisableCompression
+	}
+
+	return dialer
+}
+
+func NewWebsocket(config *Config.DbConfig) (*WS, error) {
+	ws := &WS{
+		url:       config.Url,
+		reconnect: config.Reconnect,
+		keepalive: config.Keepalive,
+		codec:     config.CodecOrDefault(),
+		binary:    config.Protocol == Config.ProtocolCBOR,
+		dialer:    newDialer(config),
+		logger:    config.LoggerOrDefault(),
+		state:     connectionState{observer: config.Observer},
+		recv:      make(chan *RPCRawResponse),
 	}
 
-	ws := &WS{ws: so}
+	queueSize := defaultQueueSize
+	if config.SendQueue != nil {
+		if config.SendQueue.Size > 0 {
+			queueSize = config.SendQueue.Size
+		}
+		ws.failFast = config.SendQueue.FailFast
+	}
+	ws.send = make(chan *RPCRequest, queueSize)
+
+	if config.Timeouts != nil {
+		ws.timeout = config.Timeouts.Timeout
+	}
+
+	if config.Dial != nil {
+		ws.headers = config.Dial.Headers
+		ws.readLimit = config.Dial.ReadLimit
+	}
 
 	// initilialize the callback maps here so we don't need to check them at runtime
-	ws.emit.once = make(map[any][]func(error, any))
-	ws.emit.when = make(map[any][]func(error, any))
+	ws.pending = newPendingRequests()
+	ws.emit.when = make(map[string]map[string]func(*RPCRawResponse))
+	ws.emit.streams = make(map[string]*LiveStream)
+	ws.session.requests = make(map[string]*RPCRequest)
 
-	ws.ctx = context.Background()
+	ws.ctx, ws.cancel = context.WithCancel(context.Background())
 
-	if len(timeout) != 0 && timeout[0] != nil {
-		ws.timeout = timeout[0].Timeout
+	// establish connection
+	so, err := ws.dial()
+	if err != nil {
+		ws.cancel()
+		return nil, err
 	}
 
+	ws.logger.Log(Config.LogInfo, "connected", "url", redactedUrl(ws.url), "protocol", so.Subprotocol())
+	ws.state.set(Config.ConnectionConnected)
+
 	// setup loops and channels
 	ws.initialise()
+	ws.connect(so, false)
 
 	return ws, nil
 
@@ -65,172 +188,449 @@ func NewWebsocket(url string, timeout ...*Config.DbTimeoutConfig) (*WS, error) {
 // --------------------------------------------------
 
 func (ws *WS) Close() error {
+	ws.lock.Lock()
+	if ws.closed {
+		ws.lock.Unlock()
+		return nil
+	}
+	ws.closed = true
+	conn := ws.conn
+	ws.lock.Unlock()
+
+	defer ws.logger.Log(Config.LogInfo, "disconnected", "url", redactedUrl(ws.url))
+	defer ws.state.set(Config.ConnectionClosed)
+	defer ws.cancel()
+	defer ws.closeStreams()
+	defer ws.pending.failAll(ErrConnectionClosed)
+
+	if conn == nil {
+		return nil
+	}
+
+	// The connection was lost before we closed, so we have nothing left to clean up
+	if atomic.LoadInt32(&conn.dropped) == 1 {
+		return nil
+	}
 
 	msg := websocket.FormatCloseMessage(1000, "")
-	return ws.ws.WriteMessage(websocket.CloseMessage, msg)
+	err := conn.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	conn.drop()
 
+	return err
 }
 
-func (ws *WS) Send(id string, method string, params []any) {
-	go func() {
-		ws.send <- &RPCRequest{
-			ID:     id,
-			Method: method,
-			Params: params,
-		}
-	}()
-}
+// SetSession stores a request which will be replayed after a reconnect
+// Requests using the same key will replace each other and move to the end of the replay order
+func (ws *WS) SetSession(key string, method string, params []any) {
+	ws.session.lock.Lock()
+	defer ws.session.lock.Unlock()
 
-type responseValue struct {
-	Value  *RPCRawResponse
-	Method string
-	Err    error
+	ws.removeSessionKey(key)
+
+	ws.session.keys = append(ws.session.keys, key)
+	ws.session.requests[key] = &RPCRequest{Method: method, Params: params}
 }
 
-// Once Subscribe to once()
-func (ws *WS) Once(id, method string) <-chan responseValue {
+// RemoveSession removes a request from the replay list
+func (ws *WS) RemoveSession(key string) {
+	ws.session.lock.Lock()
+	defer ws.session.lock.Unlock()
+
+	ws.removeSessionKey(key)
+}
 
-	out := make(chan responseValue)
+// Send sends the request and waits for its response, until ctx is done or the connection drops
+func (ws *WS) Send(ctx context.Context, id string, method string, params []any) (*RPCRawResponse, error) {
+	return ws.request(ctx, id, method, params, nil)
+}
 
-	ws.once(id, func(e error, r any) {
-		out <- responseValue{
-			Value:  r.(*RPCRawResponse),
-			Method: method,
-			Err:    e,
+// Live sends a request which starts a live query("live", or a "query" running LIVE SELECT)
+// The listener is registered before any other message is processed, so no notifications are missed
+// The live query is restarted after reconnecting, followed by a LiveActionResynced notification
+func (ws *WS) Live(ctx context.Context, id string, method string, params []any) (*LiveStream, error) {
+	stream := newLiveStream(ws)
+	stream.method = method
+	stream.params = params
+
+	// tracked before sending, so a reconnect straight after the response still restarts it
+	ws.track(stream)
+
+	result, err := ws.request(ctx, id, method, params, func(res *RPCRawResponse) {
+		liveId, err := res.LiveQueryId()
+		if err != nil {
+			return
 		}
-		close(out)
+
+		stream.lock.Lock()
+		stream.id = liveId
+		stream.lock.Unlock()
+
+		ws.when(liveId, stream.key, stream.push)
 	})
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
 
-	return out
+	if _, err := result.LiveQueryId(); err != nil {
+		stream.Close()
+		return nil, err
+	}
+	stream.response = result
 
+	if ws.isClosed() {
+		stream.Close()
+	}
+
+	return stream, nil
 }
 
-// When Subscribe to when()
-func (ws *WS) When(id, method string) <-chan responseValue {
-	// TODO: make this cancellable (use of context.Context ?)
+// Closed checks if the connection has been closed, either by Close or by giving up on reconnecting
+func (ws *WS) Closed() bool {
+	return ws.isClosed()
+}
 
-	out := make(chan responseValue)
+// Pending returns the amount of requests which are waiting for a response
+func (ws *WS) Pending() int {
+	return ws.pending.len()
+}
 
-	ws.when(id, func(e error, r any) {
-		out <- responseValue{
-			Method: method,
-			Value:  r.(*RPCRawResponse),
-			Err:    e,
-		}
-	})
+// When Subscribe to the notifications of a live query which has already been started
+// We don't know how to restart the live query, so the stream is closed when the connection is lost
+func (ws *WS) When(liveId string) *LiveStream {
+	stream := newLiveStream(ws)
+	stream.id = liveId
 
-	return out
+	ws.when(liveId, stream.key, stream.push)
+	ws.track(stream)
 
+	return stream
 }
 
 // --------------------------------------------------
 // Private methods
 // --------------------------------------------------
 
-func (ws *WS) once(id any, fn func(error, any)) {
+func (ws *WS) isClosed() bool {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
 
-	// pauses traffic in others threads, so we can add the new listener without conflicts
+	return ws.closed
+}
 
-	ws.emit.lock.Lock()
-	defer ws.emit.lock.Unlock()
+// queue hands the request over to the sender loop, requests are written in the order they're queued
+// When the queue is full we wait for space until ctx is done, or fail straight away when failFast is set
+func (ws *WS) queue(ctx context.Context, id string, method string, params []any) error {
+	request := &RPCRequest{
+		ID:     id,
+		Method: method,
+		Params: params,
+	}
+
+	if ws.failFast {
+		select {
+		case ws.send <- request:
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case ws.send <- request:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ws.ctx.Done():
+		return ErrConnectionClosed
+	}
+}
+
+func (ws *WS) request(ctx context.Context, id string, method string, params []any, onResolve func(*RPCRawResponse)) (*RPCRawResponse, error) {
+	if ws.isClosed() {
+		return nil, ErrConnectionClosed
+	}
+
+	ctx, cancel := ws.NewContext(ctx)
+	defer cancel()
 
-	ws.emit.once[id] = append(ws.emit.once[id], fn)
+	chn := ws.pending.add(id, method, onResolve)
+	// here we send the args through our websocket connection
+	if err := ws.queue(ctx, id, method, params); err != nil {
+		ws.pending.remove(id)
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		// nobody is waiting for this response anymore, so we don't want to hold on to the request
+		ws.pending.remove(id)
+		return nil, ctx.Err()
+
+	case r := <-chn:
+		if r.Err != nil {
+			return nil, r.Err
+		}
 
+		return r.Value, nil
+	}
 }
 
-// WHEN SYSTEM ISN'T BEEING USED, MAYBE FOR FUTURE IN-DATABASE EVENTS AND/OR REAL TIME stuffs.
+func (ws *WS) dial() (*websocket.Conn, error) {
+	so, _, err := ws.dialer.Dial(ws.url, ws.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	// servers which don't know the subprotocol ignore it, rather than refusing the connection
+	if ws.binary && so.Subprotocol() != string(Config.ProtocolCBOR) {
+		so.Close()
+		return nil, fmt.Errorf("%w: %s", ErrProtocolNotSupported, Config.ProtocolCBOR)
+	}
+
+	if ws.readLimit > 0 {
+		so.SetReadLimit(ws.readLimit)
+	}
+
+	return so, nil
+}
 
-func (ws *WS) when(id any, fn func(error, any)) {
+func (ws *WS) removeSessionKey(key string) {
+	if _, ok := ws.session.requests[key]; !ok {
+		return
+	}
+
+	delete(ws.session.requests, key)
+
+	for i, k := range ws.session.keys {
+		if k == key {
+			ws.session.keys = append(ws.session.keys[:i], ws.session.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (ws *WS) sessionRequests() []*RPCRequest {
+	ws.session.lock.Lock()
+	defer ws.session.lock.Unlock()
+
+	requests := make([]*RPCRequest, 0, len(ws.session.keys))
+	for _, key := range ws.session.keys {
+		requests = append(requests, ws.session.requests[key])
+	}
+
+	return requests
+}
+
+func (ws *WS) when(liveId string, key string, fn func(*RPCRawResponse)) {
 
 	// pauses traffic in others threads, so we can add the new listener without conflicts
 	ws.emit.lock.Lock()
 	defer ws.emit.lock.Unlock()
 
-	ws.emit.when[id] = append(ws.emit.when[id], fn)
+	if ws.emit.when[liveId] == nil {
+		ws.emit.when[liveId] = make(map[string]func(*RPCRawResponse))
+	}
+	ws.emit.when[liveId][key] = fn
 
 }
 
-func (ws *WS) done(id any, err error, res any) {
+// track remembers the stream, so it's closed along with the connection
+func (ws *WS) track(stream *LiveStream) {
+	ws.emit.lock.Lock()
+	defer ws.emit.lock.Unlock()
+
+	ws.emit.streams[stream.key] = stream
+}
 
-	// pauses traffic in others threads, so we can modify listeners without conflicts
+// remap moves the listener of the stream over to its new live query id
+func (ws *WS) remap(stream *LiveStream, liveId string) {
 	ws.emit.lock.Lock()
 	defer ws.emit.lock.Unlock()
 
-	// if our events map exist
-	if ws.emit.when != nil {
+	stream.lock.Lock()
+	oldId := stream.id
+	stream.id = liveId
+	stream.lock.Unlock()
 
-		// if there's some listener aiming to this id response
-		if when, ok := ws.emit.when[id]; ok {
+	delete(ws.emit.when[oldId], stream.key)
+	if len(ws.emit.when[oldId]) == 0 {
+		delete(ws.emit.when, oldId)
+	}
 
-			// dispatch the event, starting from the end, so we prioritize the new ones
-			for i := len(when) - 1; i >= 0; i-- {
+	if ws.emit.when[liveId] == nil {
+		ws.emit.when[liveId] = make(map[string]func(*RPCRawResponse))
+	}
+	ws.emit.when[liveId][stream.key] = stream.push
+}
 
-				// invoke callback
-				when[i](err, res)
+// liveStreams returns a copy of the open live streams
+func (ws *WS) liveStreams() []*LiveStream {
+	ws.emit.lock.Lock()
+	defer ws.emit.lock.Unlock()
 
-			}
-		}
+	streams := make([]*LiveStream, 0, len(ws.emit.streams))
+	for _, stream := range ws.emit.streams {
+		streams = append(streams, stream)
 	}
 
-	// if our events map exist
-	if ws.emit.once != nil {
+	return streams
+}
 
-		// if theres some listener aiming to this id response
-		if once, ok := ws.emit.once[id]; ok {
+// closeStreams closes every open live stream
+func (ws *WS) closeStreams() {
+	for _, stream := range ws.liveStreams() {
+		stream.Close()
+	}
+}
 
-			// dispatch the event, starting from the end, so we prioritize the new ones
-			for i := len(once) - 1; i >= 0; i-- {
+func (ws *WS) removeWhen(liveId string, key string) {
+	ws.emit.lock.Lock()
+	defer ws.emit.lock.Unlock()
 
-				// invoke callback
-				once[i](err, res)
+	delete(ws.emit.streams, key)
+	delete(ws.emit.when[liveId], key)
+	if len(ws.emit.when[liveId]) == 0 {
+		delete(ws.emit.when, liveId)
+	}
+}
 
-				// erase this listener
-				once[i] = nil
+// notify dispatches a live query notification to its listeners
+func (ws *WS) notify(res *RPCRawResponse) {
+	notification, err := res.LiveNotification()
+	if err != nil {
+		ws.logger.Log(Config.LogError, "failed to decode a live query notification", "error", err)
+		return
+	}
 
-			}
+	// pauses traffic in others threads, so we can read the listeners without conflicts
+	ws.emit.lock.Lock()
+	defer ws.emit.lock.Unlock()
 
-			// remove all listeners
-			ws.emit.once[id] = once[0:]
-		}
+	for _, fn := range ws.emit.when[notification.Id] {
+		fn(res)
 	}
 
 }
 
-func (ws *WS) read() (response *RPCRawResponse, err error) {
-	_, r, err := ws.ws.NextReader()
+func (ws *WS) read(conn *connection) (response *RPCRawResponse, err error) {
+	messageType, r, err := conn.ws.NextReader()
 	if err != nil {
 		return nil, err
 	}
 
-	raw, err := io.ReadAll(r)
-	if err != nil {
+	// any message shows the connection is alive, not only pongs
+	ws.extendDeadline(conn)
+
+	// the buffer is owned by the response from here on, it's only returned to the pool through Release
+	buf := getBuffer()
+	if _, err := buf.ReadFrom(r); err != nil {
+		putBuffer(buf)
 		return nil, err
 	}
 
-	return CreateRPCRawResponse(raw), nil
+	if messageType == websocket.BinaryMessage {
+		data := getBuffer()
+		err := CBORToJSON(data, buf.Bytes())
+		putBuffer(buf)
+		if err != nil {
+			// the connection itself is fine, so this is handled like a response we couldn't decode
+			putBuffer(data)
+			return &RPCRawResponse{codec: ws.codec, internalProcessingError: err}, nil
+		}
+		buf = data
+	}
+
+	response = newRPCRawResponse(buf.Bytes(), ws.codec)
+	response.buffer = buf
+
+	return response, nil
 }
 
-func (ws *WS) write(v any) (err error) {
-	w, err := ws.ws.NextWriter(websocket.TextMessage)
+// encode marshals the request before anything is written, so a value which can't be encoded
+// only fails its own request instead of leaving half a message on the connection
+// The buffer comes from the pool, write hands it back
+func (ws *WS) encode(request *RPCRequest) (*bytes.Buffer, error) {
+	buf := getBuffer()
+
+	var err error
+	if ws.binary {
+		err = EncodeCBOR(buf, request)
+	} else if codec, ok := ws.codec.(Config.StreamCodec); ok {
+		err = codec.Encode(buf, request)
+	} else {
+		var data []byte
+		if data, err = ws.codec.Marshal(request); err == nil {
+			buf.Write(data)
+		}
+	}
 	if err != nil {
-		return err
+		putBuffer(buf)
+		return nil, fmt.Errorf("failed to encode the %s request: %w", request.Method, err)
 	}
 
-	enc := json.NewEncoder(w)
-	// the default HTML escaping messes with select arrows
-	enc.SetEscapeHTML(false)
-	err = enc.Encode(v)
-	if err != nil {
-		return err
+	return buf, nil
+}
+
+func (ws *WS) write(conn *connection, buf *bytes.Buffer) error {
+	defer putBuffer(buf)
+
+	messageType := websocket.TextMessage
+	if ws.binary {
+		messageType = websocket.BinaryMessage
 	}
 
-	return w.Close()
+	return conn.ws.WriteMessage(messageType, buf.Bytes())
 }
 
+// initialise starts the main loop, which lives as long as the WS does
 func (ws *WS) initialise() {
-	send := make(chan *RPCRequest)
-	recv := make(chan *RPCRawResponse)
+	// MAIN LOOP
+	go func() {
+		for {
+			select {
+			case <-ws.ctx.Done():
+				return
+			case res := <-ws.recv:
+				if res.HasInternalError() {
+					ws.logger.Log(Config.LogError, "failed to decode a response", "error", res.internalProcessingError)
+				}
+
+				if res.IsNotification() {
+					ws.notify(res)
+				} else {
+					ws.pending.resolve(res)
+				}
+			}
+		}
+	}()
+}
+
+// connect starts the receiver & sender loops for a newly established connection
+// When replay is true, the session is replayed before any queued requests are sent
+func (ws *WS) connect(so *websocket.Conn, replay bool) error {
 	ctx, cancel := context.WithCancel(ws.ctx)
+	conn := &connection{ws: so, ctx: ctx, cancel: cancel}
+
+	ws.lock.Lock()
+	if ws.closed {
+		ws.lock.Unlock()
+		cancel()
+		so.Close()
+		return errClosed
+	}
+	ws.conn = conn
+	ws.lock.Unlock()
+
+	if ws.keepalive != nil {
+		so.SetPongHandler(func(string) error {
+			ws.extendDeadline(conn)
+			return nil
+		})
+		ws.extendDeadline(conn)
+
+		go ws.heartbeat(conn)
+	}
 
 	// RECEIVER LOOP
 	go func() {
@@ -239,57 +639,275 @@ func (ws *WS) initialise() {
 			case <-ctx.Done():
 				return
 			default:
-				res, err := ws.read()
+				res, err := ws.read(conn)
 
 				if err != nil {
-					ws.Close()
-					cancel()
+					var netErr net.Error
+					if ws.keepalive != nil && errors.As(err, &netErr) && netErr.Timeout() {
+						ws.logger.Log(Config.LogWarn, "nothing received within the keepalive deadline", "deadline", ws.keepalive.Deadline())
+					}
+					ws.lost(conn, err)
 					return
 				}
 
-				recv <- res // redirect response to: MAIN LOOP
+				select {
+				case <-ctx.Done():
+					return
+				case ws.recv <- res: // redirect response to: MAIN LOOP
+				}
 			}
 		}
 	}()
 
+	if replay {
+		err := ws.replay(conn)
+		if err == nil {
+			err = ws.resubscribe(conn)
+		}
+		if err != nil {
+			if !conn.drop() {
+				// the receiver loop beat us to it and has already started reconnecting
+				return errReconnecting
+			}
+			return err
+		}
+	}
+
 	// SENDER LOOP
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return // stops: THIS LOOP
-			case res := <-send:
-				err := ws.write(res) // marshal and send
+			case req := <-ws.send:
+				id := req.ID.(string)
+
+				// the caller has already stopped waiting(or the request was failed), so don't bother sending it
+				if !ws.pending.has(id) {
+					continue
+				}
 
+				data, err := ws.encode(req)
 				if err != nil {
-					ws.Close()
-					cancel()
+					// the connection is fine, only this request can't be sent
+					ws.pending.fail(id, err)
+					continue
+				}
+
+				if err := ws.write(conn, data); err != nil {
+					// the caller gets the actual write error, everything else in-flight fails along with the connection
+					ws.pending.fail(id, fmt.Errorf("failed to send the %s request: %w", req.Method, err))
+					ws.lost(conn, err)
 					return // stops: THIS LOOP
 				}
 			}
 		}
 	}()
 
-	// MAIN LOOP
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
+	return nil
+}
+
+// heartbeat pings the connection until it's dropped, the pongs are handled by the receiver loop
+func (ws *WS) heartbeat(conn *connection) {
+	ticker := time.NewTicker(ws.keepalive.PingInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.ctx.Done():
+			return
+		case <-ticker.C:
+			// WriteControl is safe to use alongside the sender loop
+			err := conn.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(ws.keepalive.Deadline()))
+			if err != nil {
+				ws.lost(conn, err)
 				return
-			case res := <-ws.recv:
-				if res.HasInternalError() {
-					log.Println("There was an error whilst decoding the RPC response: ", res.internalProcessingError)
-				}
+			}
+		}
+	}
+}
+
+// extendDeadline gives the connection until the next missed heartbeat to receive something
+func (ws *WS) extendDeadline(conn *connection) {
+	if ws.keepalive == nil {
+		return
+	}
 
-				ws.done(res.Id(), res.Error(), res)
+	conn.ws.SetReadDeadline(time.Now().Add(ws.keepalive.Deadline()))
+}
+
+// replay re-sends the session requests over the new connection, waiting for each one to complete
+func (ws *WS) replay(conn *connection) error {
+	for _, request := range ws.sessionRequests() {
+		r, err := ws.replayRequest(conn, request.Method, request.Params, nil)
+		if err != nil {
+			return err
+		}
+
+		// The server rejected the request, there isn't much we can do here
+		// but the connection itself is fine, so we carry on with the rest of the session
+		if r.Err != nil {
+			ws.logger.Log(Config.LogWarn, "failed to replay the session after reconnecting", "method", request.Method, "error", r.Err)
+		}
+	}
+
+	return nil
+}
+
+// resubscribe restarts every live query over the new connection
+// Live query ids belong to the connection which created them, so the old ids won't receive anything anymore
+func (ws *WS) resubscribe(conn *connection) error {
+	for _, stream := range ws.liveStreams() {
+		if stream.method == "" {
+			stream.Close()
+			continue
+		}
+		// its request was still waiting for the response, it fails along with the old connection
+		if stream.Id() == "" {
+			continue
+		}
+
+		r, err := ws.replayRequest(conn, stream.method, stream.params, func(res *RPCRawResponse) {
+			liveId, err := res.LiveQueryId()
+			if err != nil {
+				return
 			}
+
+			// this runs in the main loop, so the marker is queued before any notification for the new id
+			ws.remap(stream, liveId)
+			stream.push(newResyncedNotification(liveId, ws.codec))
+		})
+		if err != nil {
+			return err
 		}
-	}()
 
-	ws.send = send
-	ws.recv = recv
+		if r.Err == nil {
+			_, r.Err = r.Value.LiveQueryId()
+		}
+		if r.Err != nil {
+			ws.logger.Log(Config.LogWarn, "failed to restart a live query after reconnecting", "live_id", stream.Id(), "error", r.Err)
+			stream.Close()
+		}
+	}
+
+	return nil
+}
+
+// replayRequest writes the request directly to the connection(the sender loop isn't running yet) and waits for its response
+// The returned error is only set when the connection itself failed
+func (ws *WS) replayRequest(conn *connection, method string, params []any, onResolve func(*RPCRawResponse)) (responseValue, error) {
+	timeout := ws.timeout
+	if timeout <= 0 {
+		timeout = replayTimeout
+	}
+
+	id := "replay-" + strconv.FormatUint(atomic.AddUint64(&ws.replayId, 1), 16)
+
+	data, err := ws.encode(&RPCRequest{ID: id, Method: method, Params: params})
+	if err != nil {
+		return responseValue{Method: method, Err: err}, nil
+	}
+
+	chn := ws.pending.add(id, method, onResolve)
+	if err := ws.write(conn, data); err != nil {
+		ws.pending.remove(id)
+		return responseValue{}, err
+	}
+
+	select {
+	case <-conn.ctx.Done():
+		ws.pending.remove(id)
+		return responseValue{}, conn.ctx.Err()
+	case <-time.After(timeout):
+		ws.pending.remove(id)
+		return responseValue{}, errors.New("timed out whilst replaying the session: " + method)
+	case r := <-chn:
+		if r.Err == ErrConnectionClosed {
+			return r, r.Err
+		}
+		return r, nil
+	}
+}
+
+// lost is called by the read/write loops when the connection errors
+// It stops the loops for this connection and starts reconnecting if it's enabled
+func (ws *WS) lost(conn *connection, err error) {
+	if !conn.drop() {
+		return
+	}
+
+	// whatever was in-flight on this connection is gone, there's no point letting the callers wait on it
+	ws.pending.failAll(ErrConnectionClosed)
+
+	ws.lock.Lock()
+	closed := ws.closed
+	ws.lock.Unlock()
+
+	if closed {
+		return
+	}
+
+	ws.logger.Log(Config.LogWarn, "connection lost", "error", err, "reconnect", ws.reconnect != nil)
+
+	if ws.reconnect == nil {
+		ws.Close()
+		return
+	}
+
+	ws.state.set(Config.ConnectionReconnecting)
+	go ws.reconnectLoop()
 }
 
-func (ws *WS) NewContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ws.ctx, ws.timeout)
+func (ws *WS) reconnectLoop() {
+	for attempt := 0; ws.reconnect.MaxRetries == 0 || attempt < ws.reconnect.MaxRetries; attempt++ {
+		select {
+		case <-ws.ctx.Done():
+			return
+		case <-time.After(ws.reconnect.Delay(attempt)):
+		}
+
+		so, err := ws.dial()
+		if err != nil {
+			ws.logger.Log(Config.LogWarn, "reconnect attempt failed", "attempt", attempt+1, "error", err)
+			continue
+		}
+
+		err = ws.connect(so, true)
+		if err == nil {
+			ws.logger.Log(Config.LogInfo, "reconnected", "url", redactedUrl(ws.url), "attempt", attempt+1)
+			ws.state.set(Config.ConnectionConnected)
+			return
+		}
+		if err == errReconnecting || err == errClosed {
+			return
+		}
+
+		ws.logger.Log(Config.LogWarn, "reconnect attempt failed", "attempt", attempt+1, "error", err)
+	}
+
+	ws.logger.Log(Config.LogError, "giving up reconnecting", "attempts", ws.reconnect.MaxRetries)
+	ws.Close()
+}
+
+// NewContext derives a context for a request from ctx, applying the configured timeout
+func (ws *WS) NewContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if ws.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, ws.timeout)
+}
+
+// redactedUrl hides the password, in case the url contains credentials
+func redactedUrl(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+
+	return parsed.Redacted()
 }
@@ -0,0 +1,251 @@
+package surrealdb
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid json patch")
+	ErrPatchFailed  = errors.New("json patch could not be applied")
+)
+
+// applyPatches applies RFC 6902 JSON Patch operations to a decoded json document(maps, slices and values)
+// The document is modified in place where possible, the returned value must be used
+func applyPatches(doc any, patches []Patch) (any, error) {
+	var err error
+	for _, patch := range patches {
+		if doc, err = applyPatch(doc, patch); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+func applyPatch(doc any, patch Patch) (any, error) {
+	path, err := parsePointer(patch.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patch.Op {
+	case "add":
+		return patchAdd(doc, path, patch.Value)
+	case "remove":
+		doc, _, err = patchRemove(doc, path)
+		return doc, err
+	case "replace":
+		if doc, _, err = patchRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, patch.Value)
+	case "move", "copy":
+		from, err := parsePointer(patch.From)
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+		if patch.Op == "move" {
+			doc, value, err = patchRemove(doc, from)
+		} else {
+			value, err = patchGet(doc, from)
+			value = deepCopy(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, value)
+	case "test":
+		value, err := patchGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		// compare the encoded values, so it doesn't matter if numbers were decoded as json.Number or float64
+		expected, err := json.Marshal(patch.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(expected, actual) {
+			return nil, ErrPatchFailed
+		}
+		return doc, nil
+	}
+
+	return nil, errors.New("unsupported json patch operation: " + patch.Op)
+}
+
+// parsePointer splits a RFC 6901 JSON Pointer into its tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, ErrInvalidPatch
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func patchGet(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPatchFailed
+			}
+			doc = value
+		case []any:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, ErrPatchFailed
+		}
+	}
+
+	return doc, nil
+}
+
+func patchAdd(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := patchGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+		return doc, nil
+	case []any:
+		idx := len(node)
+		if last != "-" {
+			if idx, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+
+		node = append(node, nil)
+		copy(node[idx+1:], node[idx:])
+		node[idx] = value
+
+		// the slice header changed, so it has to be stored in its parent again
+		return patchSet(doc, path[:len(path)-1], node)
+	}
+
+	return nil, ErrPatchFailed
+}
+
+func patchRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := patchGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, ErrPatchFailed
+		}
+		delete(node, last)
+		return doc, value, nil
+	case []any:
+		idx, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		value := node[idx]
+		node = append(node[:idx:idx], node[idx+1:]...)
+
+		doc, err = patchSet(doc, path[:len(path)-1], node)
+		return doc, value, err
+	}
+
+	return nil, nil, ErrPatchFailed
+}
+
+// patchSet replaces the value at path, without the checks of "replace"
+func patchSet(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := patchGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+	case []any:
+		idx, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = value
+	default:
+		return nil, ErrPatchFailed
+	}
+
+	return doc, nil
+}
+
+// arrayIndex parses an array index token, which must be between 0 and max
+func arrayIndex(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrPatchFailed
+	}
+
+	return idx, nil
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, val := range v {
+			out[key] = deepCopy(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = deepCopy(val)
+		}
+		return out
+	}
+
+	return value
+}
@@ -0,0 +1,187 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
+
+var (
+	// ErrLiveQueriesUnsupported is returned when the transport can't receive live query notifications
+	ErrLiveQueriesUnsupported = errors.New("live queries are not supported by this transport")
+	// ErrUnknownLiveQuery is returned when the live query wasn't started through DB.Live, or has been killed
+	ErrUnknownLiveQuery = errors.New("unknown live query")
+	// ErrUnknownRecord is returned when a live notification doesn't tell us which record changed
+	ErrUnknownRecord = errors.New("the live notification does not contain a record id")
+
+	simpleIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+type LiveAction string
+
+const (
+	LiveActionCreate LiveAction = "CREATE"
+	LiveActionUpdate LiveAction = "UPDATE"
+	LiveActionDelete LiveAction = "DELETE"
+	// LiveActionResynced is sent once the live query has been restarted after reconnecting
+	// Changes made whilst the connection was down have been missed, so any local state should be reloaded
+	LiveActionResynced LiveAction = internal.LiveActionResynced
+)
+
+// LiveNotification is a change pushed by the database for a live query
+type LiveNotification[T any] struct {
+	Action LiveAction
+	// The id of the record which changed, when the database includes it
+	Record string
+	// The record, for DELETE this may be empty depending on the database version
+	Result T
+	// Set when the result could not be decoded into T
+	Error error
+}
+
+// LiveSubscription receives the typed notifications for a live query
+type LiveSubscription[T any] struct {
+	db            *DB
+	stream        *internal.LiveStream
+	notifications chan LiveNotification[T]
+}
+
+// liveTransport is implemented by transports which can receive live query notifications
+type liveTransport interface {
+	Live(ctx context.Context, id string, method string, params []any) (*internal.LiveStream, error)
+}
+
+// LiveQuery starts a live query for every change on the table
+// It is the same as running: LIVE SELECT * FROM table
+func LiveQuery[T any](db *DB, table string) (*LiveSubscription[T], error) {
+	return LiveQueryCtx[T](context.Background(), db, table)
+}
+
+// LiveQueryCtx is the same as LiveQuery, but the request will be cancelled when ctx is done
+func LiveQueryCtx[T any](ctx context.Context, db *DB, table string) (*LiveSubscription[T], error) {
+	return startLiveQuery[T](ctx, db, "live", table)
+}
+
+// LiveQueryWhere starts a live query for the changes on the table which match the condition
+// It is the same as running: LIVE SELECT * FROM table WHERE where
+// Values should be passed through vars, the condition is not escaped
+func LiveQueryWhere[T any](db *DB, table string, where string, vars map[string]any) (*LiveSubscription[T], error) {
+	return LiveQueryWhereCtx[T](context.Background(), db, table, where, vars)
+}
+
+// LiveQueryWhereCtx is the same as LiveQueryWhere, but the request will be cancelled when ctx is done
+func LiveQueryWhereCtx[T any](ctx context.Context, db *DB, table string, where string, vars map[string]any) (*LiveSubscription[T], error) {
+	if vars == nil {
+		vars = map[string]any{}
+	}
+
+	query := "LIVE SELECT * FROM " + escapeIdentifier(table) + " WHERE " + where
+	return startLiveQuery[T](ctx, db, "query", query, vars)
+}
+
+func startLiveQuery[T any](ctx context.Context, db *DB, method string, params ...any) (*LiveSubscription[T], error) {
+	db, err := orDefault(db)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, ok := db.transport.(liveTransport)
+	if !ok {
+		return nil, ErrLiveQueriesUnsupported
+	}
+
+	stream, err := db.startLive(ctx, transport, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &LiveSubscription[T]{
+		db:            db,
+		stream:        stream,
+		notifications: make(chan LiveNotification[T]),
+	}
+
+	go subscription.decode()
+
+	return subscription, nil
+}
+
+// ID returns the id of the live query on the database, it changes when the live query is restarted after reconnecting
+func (sub *LiveSubscription[T]) ID() string {
+	return sub.stream.Id()
+}
+
+// Notifications receives every change, it's closed once the subscription is closed
+func (sub *LiveSubscription[T]) Notifications() <-chan LiveNotification[T] {
+	return sub.notifications
+}
+
+// Close kills the live query and stops the subscription
+func (sub *LiveSubscription[T]) Close() error {
+	return sub.CloseCtx(context.Background())
+}
+
+// CloseCtx is the same as Close, but the kill request will be cancelled when ctx is done
+func (sub *LiveSubscription[T]) CloseCtx(ctx context.Context) error {
+	_, err := sub.db.killStream(ctx, sub.stream)
+	return err
+}
+
+// decode turns the raw notifications into typed ones
+func (sub *LiveSubscription[T]) decode() {
+	defer close(sub.notifications)
+
+	for res := range sub.stream.Notifications() {
+		notification := newLiveNotification[T](res)
+
+		select {
+		case <-sub.stream.Done():
+			return
+		case sub.notifications <- notification:
+		}
+	}
+}
+
+func newLiveNotification[T any](res *internal.RPCRawResponse) LiveNotification[T] {
+	notification := LiveNotification[T]{}
+
+	data, err := res.LiveNotification()
+	if err != nil {
+		notification.Error = err
+		return notification
+	}
+
+	notification.Action = LiveAction(data.Action)
+	notification.Record = data.Record
+
+	switch data.Type {
+	case jsonparser.String:
+		// Some versions only give us the id of the record which was deleted
+		if notification.Record == "" {
+			notification.Record, _ = jsonparser.ParseString(data.Result)
+		}
+	case jsonparser.Object:
+		if notification.Record == "" {
+			notification.Record, _ = jsonparser.GetString(data.Result, "id")
+		}
+		notification.Error = res.Codec().Unmarshal(data.Result, &notification.Result)
+	case jsonparser.NotExist, jsonparser.Null:
+	default:
+		notification.Error = res.Codec().Unmarshal(data.Result, &notification.Result)
+	}
+
+	return notification
+}
+
+// escapeIdentifier wraps table names which aren't plain identifiers, so they can't change the query
+func escapeIdentifier(name string) string {
+	if simpleIdentifier.MatchString(name) {
+		return name
+	}
+
+	return "⟨" + strings.ReplaceAll(name, "⟩", `\⟩`) + "⟩"
+}
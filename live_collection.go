@@ -0,0 +1,366 @@
+package surrealdb
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/buger/jsonparser"
+	"github.com/goccy/go-json"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
+
+// LiveCollectionConfig configures a LiveCollection
+type LiveCollectionConfig struct {
+	Db    *DB
+	Table string
+	// Diff uses LIVE SELECT DIFF, so the database only sends JSON Patches of what changed
+	Diff bool
+	// The context used for the initial load, when nil context.Background() is used
+	Ctx context.Context
+}
+
+// LiveCollectionChange is passed to the OnChange callbacks
+type LiveCollectionChange[T any] struct {
+	// CREATE, UPDATE, DELETE, or RESYNCED once every record has been reloaded
+	Action LiveAction
+	Id     string
+	// The record after the change, empty for DELETE and RESYNCED
+	Item T
+}
+
+// LiveCollection is an in-memory copy of a table, kept up to date by a live query
+// Changes are applied in the order the database sent them
+type LiveCollection[T any] struct {
+	db     *DB
+	table  string
+	diff   bool
+	stream *internal.LiveStream
+
+	// lock guards docs and items
+	lock sync.RWMutex
+	// docs holds the decoded json of every record, so patches can be applied to them
+	docs  map[string]any
+	items map[string]T
+
+	callbacks struct {
+		lock sync.Mutex
+		fns  []func(LiveCollectionChange[T])
+	}
+
+	done chan struct{}
+}
+
+// NewLiveCollection loads every record in the table and keeps them up to date until Close is called
+func NewLiveCollection[T any](db *DB, table string) (*LiveCollection[T], error) {
+	return NewLiveCollectionWithConfig[T](LiveCollectionConfig{Db: db, Table: table})
+}
+
+// NewLiveCollectionWithConfig is the same as NewLiveCollection, using the given configuration
+func NewLiveCollectionWithConfig[T any](config LiveCollectionConfig) (*LiveCollection[T], error) {
+	ctx := config.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	db, err := orDefault(config.Db)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, ok := db.transport.(liveTransport)
+	if !ok {
+		return nil, ErrLiveQueriesUnsupported
+	}
+
+	params := []any{config.Table}
+	if config.Diff {
+		params = append(params, true)
+	}
+
+	// The live query is started before loading the records, so nothing changed in between is missed
+	// Its notifications are queued by the stream until we start processing them
+	stream, err := db.startLive(ctx, transport, "live", params)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &LiveCollection[T]{
+		db:     db,
+		table:  config.Table,
+		diff:   config.Diff,
+		stream: stream,
+		done:   make(chan struct{}),
+	}
+
+	if err := collection.load(ctx); err != nil {
+		db.killStream(ctx, stream)
+		return nil, err
+	}
+
+	go collection.run()
+
+	return collection, nil
+}
+
+// Get returns a single record by its id
+func (collection *LiveCollection[T]) Get(id string) (T, bool) {
+	collection.lock.RLock()
+	defer collection.lock.RUnlock()
+
+	item, ok := collection.items[id]
+	return item, ok
+}
+
+// Snapshot returns a copy of every record, by id
+func (collection *LiveCollection[T]) Snapshot() map[string]T {
+	collection.lock.RLock()
+	defer collection.lock.RUnlock()
+
+	items := make(map[string]T, len(collection.items))
+	for id, item := range collection.items {
+		items[id] = item
+	}
+
+	return items
+}
+
+// Len returns the amount of records
+func (collection *LiveCollection[T]) Len() int {
+	collection.lock.RLock()
+	defer collection.lock.RUnlock()
+
+	return len(collection.items)
+}
+
+// OnChange registers a callback which is called after every change has been applied
+// Callbacks are called one at a time, so a slow callback delays the changes after it
+func (collection *LiveCollection[T]) OnChange(fn func(change LiveCollectionChange[T])) {
+	collection.callbacks.lock.Lock()
+	defer collection.callbacks.lock.Unlock()
+
+	collection.callbacks.fns = append(collection.callbacks.fns, fn)
+}
+
+// Done is closed once the collection stops receiving changes, either from Close or the connection closing
+func (collection *LiveCollection[T]) Done() <-chan struct{} {
+	return collection.done
+}
+
+// Close kills the live query, the records are kept but no longer updated
+func (collection *LiveCollection[T]) Close() error {
+	_, err := collection.db.killStream(context.Background(), collection.stream)
+	<-collection.done
+
+	return err
+}
+
+// --------------------------------------------------
+
+func (collection *LiveCollection[T]) run() {
+	defer close(collection.done)
+
+	for res := range collection.stream.Notifications() {
+		notification, err := res.LiveNotification()
+		if err != nil {
+			collection.db.logger.Log(Config.LogError, "failed to decode a live collection notification", "table", collection.table, "error", err)
+			continue
+		}
+
+		change, err := collection.apply(notification)
+		if err != nil {
+			// We're out of sync with the database, the only way back is to load everything again
+			collection.db.logger.Log(Config.LogWarn, "failed to apply a live collection notification, reloading", "table", collection.table, "action", notification.Action, "error", err)
+			change, err = collection.resync()
+		}
+		if err != nil {
+			collection.db.logger.Log(Config.LogError, "failed to reload a live collection", "table", collection.table, "error", err)
+			continue
+		}
+		if change == nil {
+			continue
+		}
+
+		collection.emit(*change)
+	}
+}
+
+func (collection *LiveCollection[T]) apply(notification *internal.LiveNotificationData) (*LiveCollectionChange[T], error) {
+	action := LiveAction(notification.Action)
+	if action == LiveActionResynced {
+		return collection.resync()
+	}
+
+	var patches []Patch
+	diff := collection.diff && notification.Type == jsonparser.Array
+	if diff {
+		if err := decodeDocument(notification.Result, &patches); err != nil {
+			return nil, err
+		}
+		// SurrealDB replaces "/" with the whole record, which it means as the root rather than the "" key
+		for i := range patches {
+			if patches[i].Path == "/" {
+				patches[i].Path = ""
+			}
+		}
+	}
+
+	id := notification.Record
+	if id == "" {
+		switch {
+		case diff:
+			id = patchedId(patches)
+		case notification.Type == jsonparser.String:
+			id, _ = jsonparser.ParseString(notification.Result)
+		default:
+			id, _ = jsonparser.GetString(notification.Result, "id")
+		}
+	}
+	if id == "" {
+		return nil, ErrUnknownRecord
+	}
+
+	change := &LiveCollectionChange[T]{Action: action, Id: id}
+
+	if action == LiveActionDelete {
+		collection.lock.Lock()
+		delete(collection.docs, id)
+		delete(collection.items, id)
+		collection.lock.Unlock()
+
+		return change, nil
+	}
+
+	collection.lock.RLock()
+	doc := collection.docs[id]
+	collection.lock.RUnlock()
+
+	var err error
+	if diff {
+		// a new record is built up from nothing, unless it's sent whole
+		if doc == nil && action == LiveActionCreate && (len(patches) == 0 || patches[0].Path != "") {
+			doc = map[string]any{}
+		}
+		// the patches are applied to a copy, so a failed patch doesn't leave a half updated record behind
+		doc, err = applyPatches(deepCopy(doc), patches)
+	} else {
+		doc = nil
+		err = decodeDocument(notification.Result, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = convertDocument(collection.db.codec, doc, &change.Item); err != nil {
+		return nil, err
+	}
+
+	collection.lock.Lock()
+	collection.docs[id] = doc
+	collection.items[id] = change.Item
+	collection.lock.Unlock()
+
+	return change, nil
+}
+
+// patchedId finds the id of the record in its patches, for notifications which don't say which record they're for
+func patchedId(patches []Patch) string {
+	for _, patch := range patches {
+		switch patch.Path {
+		case "":
+			if doc, ok := patch.Value.(map[string]any); ok {
+				if id, ok := doc["id"].(string); ok {
+					return id
+				}
+			}
+		case "/id":
+			if id, ok := patch.Value.(string); ok {
+				return id
+			}
+		}
+	}
+
+	return ""
+}
+
+// load replaces every record with the current records from the database
+func (collection *LiveCollection[T]) load(ctx context.Context) error {
+	response, err := collection.db.send(ctx, "select", collection.table)
+	if err != nil {
+		return err
+	}
+
+	result := response.Result()
+
+	docs := make(map[string]any)
+	items := make(map[string]T)
+
+	_, err = jsonparser.ArrayEach(result.Result, func(value []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if err != nil || dataType != jsonparser.Object {
+			return
+		}
+
+		id, _ := jsonparser.GetString(value, "id")
+
+		var doc any
+		if err = decodeDocument(value, &doc); err != nil {
+			return
+		}
+
+		var item T
+		if err = convertDocument(collection.db.codec, doc, &item); err != nil {
+			return
+		}
+
+		docs[id] = doc
+		items[id] = item
+	})
+	if err != nil {
+		return err
+	}
+
+	collection.lock.Lock()
+	collection.docs = docs
+	collection.items = items
+	collection.lock.Unlock()
+
+	return nil
+}
+
+func (collection *LiveCollection[T]) resync() (*LiveCollectionChange[T], error) {
+	if err := collection.load(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return &LiveCollectionChange[T]{Action: LiveActionResynced}, nil
+}
+
+func (collection *LiveCollection[T]) emit(change LiveCollectionChange[T]) {
+	collection.callbacks.lock.Lock()
+	fns := make([]func(LiveCollectionChange[T]), len(collection.callbacks.fns))
+	copy(fns, collection.callbacks.fns)
+	collection.callbacks.lock.Unlock()
+
+	for _, fn := range fns {
+		fn(change)
+	}
+}
+
+// decodeDocument decodes json, keeping numbers as json.Number so large integers survive being re-encoded
+func decodeDocument(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+// convertDocument turns a decoded json document into T, using the codec of the connection
+func convertDocument(codec Config.Codec, doc any, v any) error {
+	data, err := codec.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(data, v)
+}
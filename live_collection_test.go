@@ -0,0 +1,236 @@
+package surrealdb_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+type collectionUser struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Tags     []string `json:"tags"`
+}
+
+// collectionHandler serves a "user" table, whatever is in records is returned for "select"
+func collectionHandler(lock *sync.Mutex, records *[]any) mockHandler {
+	return func(req mockRequest) (any, string) {
+		switch req.Method {
+		case "live":
+			return liveQueryId, ""
+		case "select":
+			lock.Lock()
+			defer lock.Unlock()
+			return *records, ""
+		}
+		return nil, ""
+	}
+}
+
+func notify(mock *mockServer, conn int, action string, result any, record string) {
+	notification := map[string]any{"id": liveQueryId, "action": action, "result": result}
+	if record != "" {
+		notification["record"] = record
+	}
+	mock.push(conn, map[string]any{"result": notification})
+}
+
+func TestLiveCollection_AppliesChanges(t *testing.T) {
+	var lock sync.Mutex
+	records := []any{
+		map[string]any{"id": "user:1", "username": "sam"},
+		map[string]any{"id": "user:2", "username": "alex"},
+	}
+	mock := newMockServer(t, collectionHandler(&lock, &records))
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	collection, err := surrealdb.NewLiveCollection[collectionUser](db, "user")
+	require.NoError(t, err)
+	defer collection.Close()
+
+	require.Equal(t, []string{"live", "select"}, mock.methods(0))
+	require.Equal(t, 2, collection.Len())
+
+	changes := make(chan surrealdb.LiveCollectionChange[collectionUser], 10)
+	collection.OnChange(func(change surrealdb.LiveCollectionChange[collectionUser]) {
+		changes <- change
+	})
+
+	notify(mock, 0, "CREATE", map[string]any{"id": "user:3", "username": "kim"}, "")
+	notify(mock, 0, "UPDATE", map[string]any{"id": "user:1", "username": "samuel"}, "")
+	notify(mock, 0, "DELETE", "user:2", "")
+
+	for _, expected := range []surrealdb.LiveAction{surrealdb.LiveActionCreate, surrealdb.LiveActionUpdate, surrealdb.LiveActionDelete} {
+		select {
+		case change := <-changes:
+			require.Equal(t, expected, change.Action)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+
+	require.Equal(t, map[string]collectionUser{
+		"user:1": {ID: "user:1", Username: "samuel"},
+		"user:3": {ID: "user:3", Username: "kim"},
+	}, collection.Snapshot())
+
+	require.NoError(t, collection.Close())
+	require.Equal(t, []string{"live", "select", "kill"}, mock.methods(0))
+}
+
+func TestLiveCollection_AppliesDiffCreates(t *testing.T) {
+	var lock sync.Mutex
+	records := []any{}
+	mock := newMockServer(t, collectionHandler(&lock, &records))
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	collection, err := surrealdb.NewLiveCollectionWithConfig[collectionUser](surrealdb.LiveCollectionConfig{
+		Db:    db,
+		Table: "user",
+		Diff:  true,
+	})
+	require.NoError(t, err)
+	defer collection.Close()
+
+	changes := make(chan surrealdb.LiveCollectionChange[collectionUser], 10)
+	collection.OnChange(func(change surrealdb.LiveCollectionChange[collectionUser]) {
+		changes <- change
+	})
+
+	// what SurrealDB sends for a CREATE in diff mode, the whole record replaces the root and there's no record field
+	notify(mock, 0, "CREATE", []any{
+		map[string]any{"op": "replace", "path": "/", "value": map[string]any{"id": "user:1", "username": "sam", "tags": []string{"admin"}}},
+	}, "")
+	// a record built up field by field
+	notify(mock, 0, "CREATE", []any{
+		map[string]any{"op": "add", "path": "/id", "value": "user:2"},
+		map[string]any{"op": "add", "path": "/username", "value": "kim"},
+	}, "")
+
+	for _, expected := range []collectionUser{
+		{ID: "user:1", Username: "sam", Tags: []string{"admin"}},
+		{ID: "user:2", Username: "kim"},
+	} {
+		select {
+		case change := <-changes:
+			require.Equal(t, surrealdb.LiveActionCreate, change.Action)
+			require.Equal(t, expected.ID, change.Id)
+			require.Equal(t, expected, change.Item)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the create")
+		}
+	}
+
+	require.Equal(t, 2, collection.Len())
+	// applied without loading the table again
+	require.Equal(t, []string{"live", "select"}, mock.methods(0))
+}
+
+func TestLiveCollection_AppliesDiffs(t *testing.T) {
+	var lock sync.Mutex
+	records := []any{
+		map[string]any{"id": "user:1", "username": "sam", "tags": []string{"admin"}},
+	}
+	mock := newMockServer(t, collectionHandler(&lock, &records))
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	collection, err := surrealdb.NewLiveCollectionWithConfig[collectionUser](surrealdb.LiveCollectionConfig{
+		Db:    db,
+		Table: "user",
+		Diff:  true,
+	})
+	require.NoError(t, err)
+	defer collection.Close()
+
+	changes := make(chan surrealdb.LiveCollectionChange[collectionUser], 10)
+	collection.OnChange(func(change surrealdb.LiveCollectionChange[collectionUser]) {
+		changes <- change
+	})
+
+	notify(mock, 0, "UPDATE", []any{
+		map[string]any{"op": "replace", "path": "/username", "value": "samuel"},
+		map[string]any{"op": "add", "path": "/tags/-", "value": "owner"},
+	}, "user:1")
+
+	select {
+	case change := <-changes:
+		require.Equal(t, surrealdb.LiveActionUpdate, change.Action)
+		require.Equal(t, collectionUser{ID: "user:1", Username: "samuel", Tags: []string{"admin", "owner"}}, change.Item)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the update")
+	}
+
+	// a patch which can't be applied means we're out of sync, so everything is loaded again
+	lock.Lock()
+	records = []any{map[string]any{"id": "user:1", "username": "reloaded"}}
+	lock.Unlock()
+
+	notify(mock, 0, "UPDATE", []any{
+		map[string]any{"op": "remove", "path": "/missing"},
+	}, "user:1")
+
+	select {
+	case change := <-changes:
+		require.Equal(t, surrealdb.LiveActionResynced, change.Action)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the reload")
+	}
+
+	item, ok := collection.Get("user:1")
+	require.True(t, ok)
+	require.Equal(t, "reloaded", item.Username)
+}
+
+func TestLiveCollection_ResyncsAfterReconnecting(t *testing.T) {
+	var lock sync.Mutex
+	records := []any{map[string]any{"id": "user:1", "username": "sam"}}
+	mock := newMockServer(t, collectionHandler(&lock, &records))
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	collection, err := surrealdb.NewLiveCollection[collectionUser](db, "user")
+	require.NoError(t, err)
+	defer collection.Close()
+
+	resynced := make(chan struct{}, 1)
+	collection.OnChange(func(change surrealdb.LiveCollectionChange[collectionUser]) {
+		if change.Action == surrealdb.LiveActionResynced {
+			resynced <- struct{}{}
+		}
+	})
+
+	// a record was created whilst we were disconnected
+	lock.Lock()
+	records = append(records, map[string]any{"id": "user:2", "username": "alex"})
+	lock.Unlock()
+
+	mock.dropConnections()
+	mock.waitForConnection(1)
+
+	select {
+	case <-resynced:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the collection to resync")
+	}
+
+	require.Equal(t, 2, collection.Len())
+	require.Equal(t, []string{"live", "select"}, mock.methods(1))
+}
@@ -0,0 +1,225 @@
+package surrealdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+type liveUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+const liveQueryId = "f1a5b2c3-0000-4000-8000-000000000001"
+
+func liveHandler(req mockRequest) (any, string) {
+	switch req.Method {
+	case "live":
+		return liveQueryId, ""
+	case "query":
+		return []any{map[string]any{"status": "OK", "time": "1ms", "result": liveQueryId}}, ""
+	}
+	return nil, ""
+}
+
+func nextNotification[T any](t *testing.T, sub *surrealdb.LiveSubscription[T]) surrealdb.LiveNotification[T] {
+	select {
+	case notification, ok := <-sub.Notifications():
+		require.True(t, ok, "notifications channel was closed")
+		return notification
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a live notification")
+	}
+	return surrealdb.LiveNotification[T]{}
+}
+
+func TestLiveQuery_ReceivesTypedNotifications(t *testing.T) {
+	mock := newMockServer(t, liveHandler)
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	sub, err := surrealdb.LiveQuery[liveUser](db, "user")
+	require.NoError(t, err)
+	require.Equal(t, liveQueryId, sub.ID())
+
+	mock.push(0, map[string]any{"result": map[string]any{
+		"id":     liveQueryId,
+		"action": "CREATE",
+		"result": map[string]any{"id": "user:1", "username": "sam"},
+	}})
+	// notifications for other live queries are ignored
+	mock.push(0, map[string]any{"result": map[string]any{
+		"id":     "another-live-query",
+		"action": "CREATE",
+		"result": map[string]any{"id": "user:2", "username": "alex"},
+	}})
+	mock.push(0, map[string]any{"result": map[string]any{
+		"id":     liveQueryId,
+		"action": "DELETE",
+		"result": "user:1",
+	}})
+
+	created := nextNotification(t, sub)
+	require.NoError(t, created.Error)
+	require.Equal(t, surrealdb.LiveActionCreate, created.Action)
+	require.Equal(t, "user:1", created.Record)
+	require.Equal(t, liveUser{ID: "user:1", Username: "sam"}, created.Result)
+
+	deleted := nextNotification(t, sub)
+	require.NoError(t, deleted.Error)
+	require.Equal(t, surrealdb.LiveActionDelete, deleted.Action)
+	require.Equal(t, "user:1", deleted.Record)
+
+	require.NoError(t, sub.Close())
+	require.Equal(t, []string{"live", "kill"}, mock.methods(0))
+
+	_, ok := <-sub.Notifications()
+	require.False(t, ok)
+}
+
+func TestLiveQueryWhere_RunsLiveSelect(t *testing.T) {
+	mock := newMockServer(t, liveHandler)
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	sub, err := surrealdb.LiveQueryWhere[liveUser](db, "user-accounts", "age > $age", map[string]any{"age": 18})
+	require.NoError(t, err)
+	require.Equal(t, liveQueryId, sub.ID())
+
+	mock.lock.Lock()
+	req := mock.requests[0][0]
+	mock.lock.Unlock()
+
+	var query string
+	require.NoError(t, json.Unmarshal(req.Params[0], &query))
+	require.Equal(t, "LIVE SELECT * FROM ⟨user-accounts⟩ WHERE age > $age", query)
+
+	db.Close()
+
+	// closing the connection closes the subscription
+	select {
+	case _, ok := <-sub.Notifications():
+		require.False(t, ok)
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscription was not closed along with the connection")
+	}
+}
+
+func TestLiveQuery_ResubscribesAfterReconnecting(t *testing.T) {
+	var lock sync.Mutex
+	started := 0
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method != "live" {
+			return nil, ""
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		started++
+		return fmt.Sprintf("live-query-%d", started), ""
+	})
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	sub, err := surrealdb.LiveQuery[liveUser](db, "user")
+	require.NoError(t, err)
+	require.Equal(t, "live-query-1", sub.ID())
+
+	rawId, err := db.Live("post")
+	require.NoError(t, err)
+	require.Equal(t, "live-query-2", rawId)
+
+	raw, err := db.LiveNotifications(rawId.(string))
+	require.NoError(t, err)
+
+	mock.dropConnections()
+	mock.waitForConnection(1)
+
+	resynced := nextNotification(t, sub)
+	require.Equal(t, surrealdb.LiveActionResynced, resynced.Action)
+
+	select {
+	case res := <-raw:
+		action, _ := jsonparser.GetString(res.Result().Result, "action")
+		require.Equal(t, "RESYNCED", action)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the resynced marker")
+	}
+
+	newId := sub.ID()
+	require.Contains(t, []string{"live-query-3", "live-query-4"}, newId)
+
+	// the old id is gone, only the new one is delivered
+	mock.push(1, map[string]any{"result": map[string]any{
+		"id":     "live-query-1",
+		"action": "CREATE",
+		"result": map[string]any{"id": "user:1", "username": "stale"},
+	}})
+	mock.push(1, map[string]any{"result": map[string]any{
+		"id":     newId,
+		"action": "CREATE",
+		"result": map[string]any{"id": "user:2", "username": "fresh"},
+	}})
+
+	created := nextNotification(t, sub)
+	require.Equal(t, surrealdb.LiveActionCreate, created.Action)
+	require.Equal(t, "fresh", created.Result.Username)
+
+	// killing by the id we were given uses the id the database knows about
+	_, err = db.Kill(rawId.(string))
+	require.NoError(t, err)
+
+	methods := mock.methods(1)
+	require.Equal(t, []string{"live", "live", "kill"}, methods)
+
+	mock.lock.Lock()
+	killed := string(mock.requests[1][2].Params[0])
+	mock.lock.Unlock()
+	require.NotEqual(t, `"live-query-2"`, killed)
+
+	_, err = db.LiveNotifications(rawId.(string))
+	require.Equal(t, surrealdb.ErrUnknownLiveQuery, err)
+}
+
+func TestLive_StreamClosedBeforeItIsTracked(t *testing.T) {
+	mock := newMockServer(t, liveHandler)
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+
+	// the connection closes after the live query started, but before Live has finished with it
+	db.Intercept(func(next surrealdb.Invoker) surrealdb.Invoker {
+		return func(ctx context.Context, method string, params []any) (*surrealdb.RawResponse, error) {
+			response, err := next(ctx, method, params)
+			if method == "live" {
+				db.Close()
+			}
+			return response, err
+		}
+	})
+
+	liveId, err := db.Live("user")
+	require.NoError(t, err)
+
+	// the stream was already closed, so it isn't kept around
+	_, err = db.LiveNotifications(liveId.(string))
+	require.Equal(t, surrealdb.ErrUnknownLiveQuery, err)
+}
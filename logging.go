@@ -0,0 +1,164 @@
+package surrealdb
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+// redacted replaces credentials in logged params
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the parts of a key which mark its value as a credential
+var sensitiveKeys = []string{"pass", "secret", "token", "credential"}
+
+// signinLoggedKeys are the only signin/signup variables which are logged, scopes can take
+// their credentials under any name
+var signinLoggedKeys = map[string]bool{"NS": true, "DB": true, "SC": true}
+
+// logRequest logs the start of a request, it returns when the request started so logResponse can log its duration
+func (db *DB) logRequest(id string, method string, params []any) time.Time {
+	if db.logger.Enabled(Config.LogDebug) {
+		db.logger.Log(Config.LogDebug, "request started", "method", method, "id", id, "params", db.redactParams(method, params))
+	}
+
+	return time.Now()
+}
+
+// logResponse logs the end of a request, auth requests are also logged at the info level
+// Failed requests are a warning, or an error when the connection failed rather than the request
+func (db *DB) logResponse(id string, method string, started time.Time, err error) {
+	duration := time.Since(started)
+
+	if err != nil {
+		level := Config.LogWarn
+		if errors.Is(err, ErrConnection) {
+			level = Config.LogError
+		}
+		db.logger.Log(level, "request failed", "method", method, "id", id, "duration", duration, "error", err)
+	} else {
+		db.logger.Log(Config.LogDebug, "request finished", "method", method, "id", id, "duration", duration)
+	}
+
+	if !authMethods[method] {
+		return
+	}
+
+	switch {
+	case err != nil:
+		db.logger.Log(Config.LogWarn, "authentication failed", "method", method, "error", err)
+	case method == "invalidate":
+		db.logger.Log(Config.LogInfo, "session invalidated")
+	default:
+		db.logger.Log(Config.LogInfo, "authenticated", "method", method)
+	}
+}
+
+// redactParams copies the params for logging, with any credentials in them replaced
+// Values are round-tripped through the codec, so the credentials in structs(like UserInfo) are found too
+func (db *DB) redactParams(method string, params []any) []any {
+	logged := make([]any, len(params))
+
+	for i, param := range params {
+		switch {
+		// the only param is the token
+		case method == "authenticate":
+			logged[i] = redacted
+		case method == "signin" || method == "signup":
+			logged[i] = db.redactSignin(param)
+		// let key value
+		case method == "let" && i == 1 && len(params) > 0:
+			if key, ok := params[0].(string); ok && isSensitiveKey(key) {
+				logged[i] = redacted
+				continue
+			}
+			logged[i] = db.redactValue(param)
+		default:
+			logged[i] = db.redactValue(param)
+		}
+	}
+
+	return logged
+}
+
+func (db *DB) redactValue(value any) any {
+	switch info := value.(type) {
+	case nil, string, bool, int, int64, float64:
+		return value
+	// the password can be sent under any name, see UserInfo.PasswordField
+	case UserInfo:
+		info.Password = redacted
+		value = info
+	case *UserInfo:
+		if info != nil {
+			copied := *info
+			copied.Password = redacted
+			value = copied
+		}
+	}
+
+	data, err := db.codec.Marshal(value)
+	if err != nil {
+		return redacted
+	}
+
+	var decoded any
+	if err := db.codec.Unmarshal(data, &decoded); err != nil {
+		return redacted
+	}
+
+	return redactDecoded(decoded)
+}
+
+// redactSignin redacts every signin/signup variable, apart from the namespace, database and scope
+func (db *DB) redactSignin(value any) any {
+	vars, ok := db.redactValue(value).(map[string]any)
+	if !ok {
+		return redacted
+	}
+
+	for key := range vars {
+		if !signinLoggedKeys[strings.ToUpper(key)] {
+			vars[key] = redacted
+		}
+	}
+
+	return vars
+}
+
+func redactDecoded(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for key, v := range value {
+			if isSensitiveKey(key) {
+				copied[key] = redacted
+			} else {
+				copied[key] = redactDecoded(v)
+			}
+		}
+		return copied
+
+	case []any:
+		copied := make([]any, len(value))
+		for i, v := range value {
+			copied[i] = redactDecoded(v)
+		}
+		return copied
+	}
+
+	return value
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,160 @@
+package surrealdb_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+type logEvent struct {
+	level Config.LogLevel
+	msg   string
+	attrs map[string]any
+}
+
+// recordingLogger keeps every event, at every level
+type recordingLogger struct {
+	lock   sync.Mutex
+	events []logEvent
+}
+
+func (logger *recordingLogger) Enabled(Config.LogLevel) bool { return true }
+
+func (logger *recordingLogger) Log(level Config.LogLevel, msg string, attrs ...any) {
+	event := logEvent{level: level, msg: msg, attrs: map[string]any{}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		event.attrs[fmt.Sprint(attrs[i])] = attrs[i+1]
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	logger.events = append(logger.events, event)
+}
+
+func (logger *recordingLogger) find(msg string) []logEvent {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	var found []logEvent
+	for _, event := range logger.events {
+		if event.msg == msg {
+			found = append(found, event)
+		}
+	}
+
+	return found
+}
+
+func TestLogger_RequestAndConnectionEvents(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "query" {
+			return nil, "There was a problem with the query"
+		}
+		return "", ""
+	})
+
+	logger := &recordingLogger{}
+
+	config := mock.config()
+	config.Logger = logger
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+
+	require.Len(t, logger.find("connected"), 1)
+
+	_, err = db.Signin(surrealdb.UserInfo{User: "root", Password: "s3cret", PasswordField: "pw"})
+	require.NoError(t, err)
+	_, err = db.Let("api_token", "t0ken")
+	require.NoError(t, err)
+	_, err = db.Query("SELECT * FROM user", map[string]any{"name": "bob", "password": "hunter2"})
+	require.Error(t, err)
+
+	started := logger.find("request started")
+	require.Len(t, started, 3)
+	require.Equal(t, "signin", started[0].attrs["method"])
+	require.NotEmpty(t, started[0].attrs["id"])
+
+	// none of the credentials should end up in the logs
+	logged := fmt.Sprint(started)
+	for _, secret := range []string{"s3cret", "t0ken", "hunter2"} {
+		require.False(t, strings.Contains(logged, secret), logged)
+	}
+	require.True(t, strings.Contains(logged, "bob"), logged)
+
+	require.Len(t, logger.find("request finished"), 2)
+	failed := logger.find("request failed")
+	require.Len(t, failed, 1)
+	require.Equal(t, "query", failed[0].attrs["method"])
+	require.NotNil(t, failed[0].attrs["duration"])
+	require.Equal(t, Config.LogWarn, failed[0].level)
+
+	authenticated := logger.find("authenticated")
+	require.Len(t, authenticated, 1)
+	require.Equal(t, Config.LogInfo, authenticated[0].level)
+
+	require.NoError(t, db.Close())
+	require.Len(t, logger.find("disconnected"), 1)
+
+	// the request never reached the database
+	_, err = db.Query("SELECT * FROM user", nil)
+	require.Error(t, err)
+	failed = logger.find("request failed")
+	require.Len(t, failed, 2)
+	require.Equal(t, Config.LogError, failed[1].level)
+}
+
+func TestLogger_ConnectionLost(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	logger := &recordingLogger{}
+
+	config := mock.config()
+	config.Logger = logger
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.waitForConnection(0)
+	mock.dropConnections()
+
+	waitFor(t, 2*time.Second, func() bool { return len(logger.find("connection lost")) == 1 })
+	require.Equal(t, Config.LogWarn, logger.find("connection lost")[0].level)
+}
+
+func TestLogger_RedactsScopeVariablesWithAnyName(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return testToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix()}), ""
+	})
+
+	logger := &recordingLogger{}
+
+	config := mock.config()
+	config.Logger = logger
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = surrealdb.SigninScope(db, "test", "app", "account", map[string]any{"email": "sam@example.com", "pwd": "hunter2", "key": "k3y"})
+	require.NoError(t, err)
+
+	started := logger.find("request started")
+	require.Len(t, started, 1)
+	require.Equal(t, []any{map[string]any{
+		"NS":    "test",
+		"DB":    "app",
+		"SC":    "account",
+		"email": "[REDACTED]",
+		"pwd":   "[REDACTED]",
+		"key":   "[REDACTED]",
+	}}, started[0].attrs["params"])
+}
@@ -0,0 +1,211 @@
+package surrealdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+// DefaultDurationBuckets are the upper bounds(in seconds) of the request duration histogram
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusCollector is an observer which keeps request and connection metrics, it serves them in the
+// Prometheus text format as a http.Handler. The same collector can observe several connections:
+//
+//	surrealdb_requests_total{method,status}                counter, status is ok or error
+//	surrealdb_request_duration_seconds{method}             histogram
+//	surrealdb_request_param_bytes_total{method}            counter
+//	surrealdb_requests_in_flight                           gauge
+//	surrealdb_connections{state}                           gauge, state is connected or reconnecting
+//	surrealdb_connection_state_changes_total{state}        counter
+type PrometheusCollector struct {
+	buckets []float64
+
+	lock         sync.Mutex
+	requests     map[requestKey]uint64
+	durations    map[string]*histogram
+	paramBytes   map[string]uint64
+	inFlight     int64
+	connections  map[Config.ConnectionState]int64
+	stateChanges map[Config.ConnectionState]uint64
+}
+
+type requestKey struct {
+	method string
+	status string
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusCollector creates a collector, using DefaultDurationBuckets when no buckets are given
+func NewPrometheusCollector(buckets ...float64) *PrometheusCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &PrometheusCollector{
+		buckets:      buckets,
+		requests:     make(map[requestKey]uint64),
+		durations:    make(map[string]*histogram),
+		paramBytes:   make(map[string]uint64),
+		connections:  make(map[Config.ConnectionState]int64),
+		stateChanges: make(map[Config.ConnectionState]uint64),
+	}
+}
+
+func (c *PrometheusCollector) OnRequestStart(ctx context.Context, req Config.RequestStart) context.Context {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.inFlight++
+	c.paramBytes[req.Method] += uint64(req.ParamSize)
+
+	return ctx
+}
+
+func (c *PrometheusCollector) OnRequestEnd(_ context.Context, req Config.RequestEnd) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.inFlight--
+
+	status := "ok"
+	if req.Err != nil {
+		status = "error"
+	}
+	c.requests[requestKey{method: req.Method, status: status}]++
+
+	h := c.durations[req.Method]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		c.durations[req.Method] = h
+	}
+
+	seconds := req.Duration.Seconds()
+	for i, bound := range c.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (c *PrometheusCollector) OnConnectionState(from Config.ConnectionState, to Config.ConnectionState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.connections[from]--
+	c.connections[to]++
+	c.stateChanges[to]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (c *PrometheusCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format
+// They're rendered before writing, so a slow writer doesn't hold up the requests being observed
+func (c *PrometheusCollector) WriteTo(w io.Writer) (int64, error) {
+	return c.render().WriteTo(w)
+}
+
+// render formats the metrics while holding the lock
+func (c *PrometheusCollector) render() *bytes.Buffer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	b := &bytes.Buffer{}
+
+	b.WriteString("# HELP surrealdb_requests_total Requests sent to SurrealDB, by method and status.\n")
+	b.WriteString("# TYPE surrealdb_requests_total counter\n")
+	keys := make([]requestKey, 0, len(c.requests))
+	for key := range c.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(b, "surrealdb_requests_total{method=%s,status=%s} %d\n", labelValue(key.method), labelValue(key.status), c.requests[key])
+	}
+
+	b.WriteString("# HELP surrealdb_request_duration_seconds How long requests to SurrealDB took, by method.\n")
+	b.WriteString("# TYPE surrealdb_request_duration_seconds histogram\n")
+	for _, method := range sortedKeys(c.durations) {
+		h := c.durations[method]
+		label := labelValue(method)
+
+		var cumulative uint64
+		for i, bound := range c.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "surrealdb_request_duration_seconds_bucket{method=%s,le=\"%s\"} %d\n", label, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(b, "surrealdb_request_duration_seconds_bucket{method=%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(b, "surrealdb_request_duration_seconds_sum{method=%s} %s\n", label, formatFloat(h.sum))
+		fmt.Fprintf(b, "surrealdb_request_duration_seconds_count{method=%s} %d\n", label, h.count)
+	}
+
+	b.WriteString("# HELP surrealdb_request_param_bytes_total Size of the params sent to SurrealDB, by method.\n")
+	b.WriteString("# TYPE surrealdb_request_param_bytes_total counter\n")
+	for _, method := range sortedKeys(c.paramBytes) {
+		fmt.Fprintf(b, "surrealdb_request_param_bytes_total{method=%s} %d\n", labelValue(method), c.paramBytes[method])
+	}
+
+	b.WriteString("# HELP surrealdb_requests_in_flight Requests waiting for their response.\n")
+	b.WriteString("# TYPE surrealdb_requests_in_flight gauge\n")
+	fmt.Fprintf(b, "surrealdb_requests_in_flight %d\n", c.inFlight)
+
+	b.WriteString("# HELP surrealdb_connections Connections to SurrealDB, by state.\n")
+	b.WriteString("# TYPE surrealdb_connections gauge\n")
+	for _, state := range []Config.ConnectionState{Config.ConnectionConnected, Config.ConnectionReconnecting} {
+		fmt.Fprintf(b, "surrealdb_connections{state=%s} %d\n", labelValue(state.String()), c.connections[state])
+	}
+
+	b.WriteString("# HELP surrealdb_connection_state_changes_total Changes of the connection state, by the new state.\n")
+	b.WriteString("# TYPE surrealdb_connection_state_changes_total counter\n")
+	for _, state := range []Config.ConnectionState{Config.ConnectionConnected, Config.ConnectionReconnecting, Config.ConnectionClosed} {
+		fmt.Fprintf(b, "surrealdb_connection_state_changes_total{state=%s} %d\n", labelValue(state.String()), c.stateChanges[state])
+	}
+
+	return b
+}
+
+// labelValue quotes and escapes a label value, as the text format expects
+func labelValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,193 @@
+package surrealdb_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+type mockRequest struct {
+	ID     string            `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// mockHandler returns the "result" for a request, or an error message which is sent as an rpc error
+type mockHandler func(req mockRequest) (any, string)
+
+// mockServer is a tiny fake of the SurrealDB rpc endpoint, so we can test the connection
+// handling without having a database running
+type mockServer struct {
+	t       *testing.T
+	server  *httptest.Server
+	handler mockHandler
+
+	lock        sync.Mutex
+	conns       []*websocket.Conn
+	headers     []http.Header
+	requests    [][]mockRequest
+	connections chan int
+	// ignorePings stops new connections from answering pings, like a half-open connection would
+	ignorePings bool
+}
+
+func newMockServer(t *testing.T, handler mockHandler) *mockServer {
+	mock := &mockServer{t: t, handler: handler, connections: make(chan int, 16)}
+	if mock.handler == nil {
+		mock.handler = func(req mockRequest) (any, string) { return nil, "" }
+	}
+
+	upgrader := websocket.Upgrader{}
+	mock.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		mock.lock.Lock()
+		idx := len(mock.conns)
+		mock.conns = append(mock.conns, conn)
+		mock.headers = append(mock.headers, r.Header.Clone())
+		mock.requests = append(mock.requests, nil)
+		if mock.ignorePings {
+			conn.SetPingHandler(func(string) error { return nil })
+		}
+		mock.lock.Unlock()
+
+		mock.connections <- idx
+
+		mock.serve(idx, conn)
+	}))
+
+	t.Cleanup(mock.server.Close)
+
+	return mock
+}
+
+func (mock *mockServer) serve(idx int, conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req mockRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			mock.t.Errorf("mock server received invalid request: %s", err)
+			return
+		}
+
+		mock.lock.Lock()
+		mock.requests[idx] = append(mock.requests[idx], req)
+		mock.lock.Unlock()
+
+		result, errMessage := mock.handler(req)
+
+		response := map[string]any{"id": req.ID}
+		if errMessage != "" {
+			response["error"] = map[string]any{"code": -32000, "message": errMessage}
+		} else {
+			response["result"] = result
+		}
+
+		mock.lock.Lock()
+		err = conn.WriteJSON(response)
+		mock.lock.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (mock *mockServer) url() string {
+	return "ws" + strings.TrimPrefix(mock.server.URL, "http") + "/rpc"
+}
+
+func (mock *mockServer) config() *Config.DbConfig {
+	return &Config.DbConfig{
+		Url:      mock.url(),
+		Timeouts: &Config.DbTimeoutConfig{Timeout: 2 * time.Second},
+	}
+}
+
+// setIgnorePings changes whether connections established from now on answer pings
+func (mock *mockServer) setIgnorePings(ignore bool) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	mock.ignorePings = ignore
+}
+
+// dropConnections closes every open connection from the server side
+func (mock *mockServer) dropConnections() {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	for _, conn := range mock.conns {
+		conn.Close()
+	}
+}
+
+// push sends a message to the client which isn't a response to any request, like a live query notification
+func (mock *mockServer) push(conn int, message any) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	if err := mock.conns[conn].WriteJSON(message); err != nil {
+		mock.t.Errorf("mock server failed to push message: %s", err)
+	}
+}
+
+// waitForConnection waits until the n-th(starting at 0) connection has been established
+func (mock *mockServer) waitForConnection(n int) {
+	for {
+		select {
+		case idx := <-mock.connections:
+			if idx >= n {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			mock.t.Fatalf("timed out waiting for connection %d", n)
+		}
+	}
+}
+
+// handshake returns the headers the n-th connection was established with
+func (mock *mockServer) handshake(conn int) http.Header {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	return mock.headers[conn]
+}
+
+func (mock *mockServer) methods(conn int) []string {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+
+	var methods []string
+	if conn < len(mock.requests) {
+		for _, req := range mock.requests[conn] {
+			methods = append(methods, req.Method)
+		}
+	}
+
+	return methods
+}
+
+// waitFor polls condition until it's true, failing the test once timeout is reached
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition was not met within %s", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
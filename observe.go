@@ -0,0 +1,76 @@
+package surrealdb
+
+import (
+	"context"
+	"time"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+// observeStart tells the observer about the request, returning the context it should be sent with
+func (db *DB) observeStart(ctx context.Context, id string, method string, params []any) (context.Context, Config.RequestStart) {
+	req := Config.RequestStart{Method: method, ID: id}
+	if db.observer == nil {
+		return ctx, req
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req.ParamSize = db.paramSize(params)
+
+	if observed := db.observer.OnRequestStart(ctx, req); observed != nil {
+		ctx = observed
+	}
+
+	return ctx, req
+}
+
+func (db *DB) observeEnd(ctx context.Context, req Config.RequestStart, started time.Time, err error) {
+	if db.observer == nil {
+		return
+	}
+
+	db.observer.OnRequestEnd(ctx, Config.RequestEnd{RequestStart: req, Duration: time.Since(started), Err: err})
+}
+
+// paramSize encodes the params to find their size, it's only done when there's an observer
+func (db *DB) paramSize(params []any) int {
+	if len(params) == 0 {
+		return 0
+	}
+
+	if codec, ok := db.codec.(Config.StreamCodec); ok {
+		var counter countingWriter
+		if codec.Encode(&counter, params) != nil {
+			return 0
+		}
+		// json encoders end with a newline, which Marshal doesn't include
+		if counter.last == '\n' {
+			return counter.n - 1
+		}
+		return counter.n
+	}
+
+	data, err := db.codec.Marshal(params)
+	if err != nil {
+		return 0
+	}
+
+	return len(data)
+}
+
+// countingWriter discards everything written to it, only keeping count of the bytes
+type countingWriter struct {
+	n    int
+	last byte
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.n += len(p)
+		w.last = p[len(p)-1]
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,291 @@
+package surrealdb_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+type stateChange struct {
+	from Config.ConnectionState
+	to   Config.ConnectionState
+}
+
+// recordingObserver keeps every request and state change
+type recordingObserver struct {
+	lock   sync.Mutex
+	starts []Config.RequestStart
+	ends   []Config.RequestEnd
+	states []stateChange
+}
+
+type observedKey struct{}
+
+func (observer *recordingObserver) OnRequestStart(ctx context.Context, req Config.RequestStart) context.Context {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	observer.starts = append(observer.starts, req)
+
+	return context.WithValue(ctx, observedKey{}, req.ID)
+}
+
+func (observer *recordingObserver) OnRequestEnd(ctx context.Context, req Config.RequestEnd) {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	if ctx.Value(observedKey{}) != req.ID {
+		panic("OnRequestEnd didn't receive the context returned by OnRequestStart")
+	}
+	observer.ends = append(observer.ends, req)
+}
+
+func (observer *recordingObserver) OnConnectionState(from Config.ConnectionState, to Config.ConnectionState) {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	observer.states = append(observer.states, stateChange{from: from, to: to})
+}
+
+func (observer *recordingObserver) stateCount() int {
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	return len(observer.states)
+}
+
+func TestObserver_RequestsAndConnectionState(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "query" {
+			return nil, "There was a problem with the query"
+		}
+		return "", ""
+	})
+
+	observer := &recordingObserver{}
+
+	config := mock.config()
+	config.Reconnect = &Config.DbReconnectConfig{InitialDelay: 10 * time.Millisecond}
+	config.Observer = observer
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+
+	_, err = db.Use("test", "test")
+	require.NoError(t, err)
+	_, err = db.Query("SELECT * FROM user", nil)
+	require.Error(t, err)
+
+	mock.waitForConnection(0)
+	mock.dropConnections()
+	mock.waitForConnection(1)
+	waitFor(t, 2*time.Second, func() bool { return observer.stateCount() == 3 })
+
+	require.NoError(t, db.Close())
+
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	require.Len(t, observer.starts, 2)
+	require.Equal(t, "use", observer.starts[0].Method)
+	require.NotEmpty(t, observer.starts[0].ID)
+	require.Equal(t, len(`["test","test"]`), observer.starts[0].ParamSize)
+
+	require.Len(t, observer.ends, 2)
+	require.Equal(t, observer.starts[0], observer.ends[0].RequestStart)
+	require.NoError(t, observer.ends[0].Err)
+	require.Error(t, observer.ends[1].Err)
+	require.True(t, observer.ends[1].Duration > 0)
+
+	require.Equal(t, []stateChange{
+		{from: Config.ConnectionConnecting, to: Config.ConnectionConnected},
+		{from: Config.ConnectionConnected, to: Config.ConnectionReconnecting},
+		{from: Config.ConnectionReconnecting, to: Config.ConnectionConnected},
+		{from: Config.ConnectionConnected, to: Config.ConnectionClosed},
+	}, observer.states)
+}
+
+func TestObserver_LiveQueriesAreObservedAndRetried(t *testing.T) {
+	var lock sync.Mutex
+	expired := true
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch req.Method {
+		case "authenticate":
+			expired = false
+		case "live":
+			if expired {
+				return nil, "There was a problem with the database: The token has expired"
+			}
+		}
+		return liveHandler(req)
+	})
+
+	observer := &recordingObserver{}
+	logger := &recordingLogger{}
+
+	config := mock.config()
+	config.Observer = observer
+	config.Logger = logger
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.EnableTokenRefresh(surrealdb.TokenRefreshConfig{
+		Refresh: func(ctx context.Context) (string, error) {
+			return testToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix()}), nil
+		},
+	})
+
+	sub, err := surrealdb.LiveQuery[liveUser](db, "user")
+	require.NoError(t, err)
+	require.NoError(t, sub.Close())
+
+	require.Equal(t, []string{"live", "authenticate", "live", "kill"}, mock.methods(0))
+
+	observer.lock.Lock()
+	defer observer.lock.Unlock()
+
+	var methods []string
+	for _, end := range observer.ends {
+		require.NoError(t, end.Err)
+		methods = append(methods, end.Method)
+	}
+	require.Equal(t, []string{"authenticate", "live", "kill"}, methods)
+
+	require.Len(t, logger.find("request started"), 3)
+	require.Len(t, logger.find("request finished"), 3)
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	collector := surrealdb.NewPrometheusCollector(0.1, 1)
+
+	collector.OnConnectionState(Config.ConnectionConnecting, Config.ConnectionConnected)
+	ctx := collector.OnRequestStart(context.Background(), Config.RequestStart{Method: "query", ID: "1", ParamSize: 20})
+	collector.OnRequestStart(ctx, Config.RequestStart{Method: "select", ID: "2", ParamSize: 6})
+	collector.OnRequestEnd(ctx, Config.RequestEnd{RequestStart: Config.RequestStart{Method: "query", ID: "1"}, Duration: 50 * time.Millisecond})
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		`surrealdb_requests_total{method="query",status="ok"} 1`,
+		`surrealdb_request_duration_seconds_bucket{method="query",le="0.1"} 1`,
+		`surrealdb_request_duration_seconds_bucket{method="query",le="1"} 1`,
+		`surrealdb_request_duration_seconds_bucket{method="query",le="+Inf"} 1`,
+		`surrealdb_request_duration_seconds_sum{method="query"} 0.05`,
+		`surrealdb_request_duration_seconds_count{method="query"} 1`,
+		`surrealdb_request_param_bytes_total{method="query"} 20`,
+		`surrealdb_request_param_bytes_total{method="select"} 6`,
+		`surrealdb_requests_in_flight 1`,
+		`surrealdb_connections{state="connected"} 1`,
+		`surrealdb_connection_state_changes_total{state="connected"} 1`,
+	} {
+		require.True(t, strings.Contains(body, line+"\n"), "%s not found in:\n%s", line, body)
+	}
+}
+
+// blockingWriter blocks every write until release is closed, like a scraper which stopped reading
+type blockingWriter struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.writing <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return len(p), nil
+}
+
+func TestPrometheusCollector_SlowScrapeDoesNotBlockRequests(t *testing.T) {
+	collector := surrealdb.NewPrometheusCollector()
+
+	w := &blockingWriter{writing: make(chan struct{}, 1), release: make(chan struct{})}
+	written := make(chan struct{})
+	go func() {
+		collector.WriteTo(w)
+		close(written)
+	}()
+	<-w.writing
+
+	observed := make(chan struct{})
+	go func() {
+		req := Config.RequestStart{Method: "query", ID: "1"}
+		ctx := collector.OnRequestStart(context.Background(), req)
+		collector.OnRequestEnd(ctx, Config.RequestEnd{RequestStart: req, Duration: time.Millisecond})
+		close(observed)
+	}()
+
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Fatal("observing a request waited for the scrape")
+	}
+
+	close(w.release)
+	<-written
+}
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (tracer *recordingTracer) Start(ctx context.Context, name string) (context.Context, surrealdb.Span) {
+	span := &recordedSpan{name: name, attrs: map[string]any{}}
+	tracer.spans = append(tracer.spans, span)
+
+	return ctx, span
+}
+
+func (span *recordedSpan) SetAttribute(key string, value any) { span.attrs[key] = value }
+func (span *recordedSpan) RecordError(err error)              { span.err = err }
+func (span *recordedSpan) End()                               { span.ended = true }
+
+func TestTracingObserver(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return nil, "There was a problem with the query"
+	})
+
+	tracer := &recordingTracer{}
+
+	config := mock.config()
+	config.Observer = surrealdb.NewTracingObserver(tracer)
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Query("SELECT * FROM user", nil)
+	require.Error(t, err)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	require.Equal(t, "surrealdb.query", span.name)
+	require.Equal(t, "surrealdb", span.attrs["db.system"])
+	require.Equal(t, "query", span.attrs["db.operation"])
+	require.Error(t, span.err)
+	require.True(t, span.ended)
+}
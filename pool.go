@@ -0,0 +1,542 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
+
+var (
+	// ErrPoolClosed is returned when sending through a pool which has been closed
+	ErrPoolClosed = errors.New("the connection pool has been closed")
+
+	errSessionChanged = errors.New("the session kept changing while opening a connection")
+)
+
+// maxOpenAttempts is how many times a connection is dialed again, when the session changed while dialing it
+const maxOpenAttempts = 3
+
+// sessionMethods change the state of a connection, so they're sent to every pooled connection
+var sessionMethods = map[string]bool{
+	"use":          true,
+	"signin":       true,
+	"authenticate": true,
+	"invalidate":   true,
+	"let":          true,
+	"unset":        true,
+}
+
+// Pool holds multiple websocket connections behind a single DB handle
+// Every request is routed to the connection with the least requests in-flight
+type Pool struct {
+	*DB
+
+	transport *poolTransport
+}
+
+// NewPool Creates a pool of websocket connections, which exposes the same methods as DB
+func NewPool(config *Config.DbConfig, poolConfig *Config.DbPoolConfig) (*Pool, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport, err := newPoolTransport(config, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := newDB(config, transport, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pool{DB: db, transport: transport}, nil
+}
+
+// Size returns the amount of open connections in the pool
+func (pool *Pool) Size() int {
+	pool.transport.lock.Lock()
+	defer pool.transport.lock.Unlock()
+
+	return len(pool.transport.conns)
+}
+
+// --------------------------------------------------
+
+type pooledConnection struct {
+	ws       *internal.WS
+	inflight int
+	lastUsed time.Time
+	// live is the amount of open live queries, which pins the connection so it isn't reaped
+	live int
+}
+
+// poolTransport implements Transport by spreading the requests over multiple websocket connections
+type poolTransport struct {
+	config     *Config.DbConfig
+	poolConfig Config.DbPoolConfig
+
+	// lock guards conns, opening, closed and the inflight/lastUsed of every connection
+	lock  sync.Mutex
+	conns []*pooledConnection
+	// opening is the amount of connections being dialed, they're counted towards MaxConnections
+	// before they're added to conns, so concurrent requests don't each open their own
+	opening int
+	// opened is signalled when a connection finished opening, or the pool was closed
+	opened *sync.Cond
+	closed bool
+
+	// session holds the requests needed to bring a new connection into the same state as the others
+	// it's held while broadcasting, version changes with every recorded change so a connection
+	// dialed in the meantime knows it missed one
+	session struct {
+		lock    sync.Mutex
+		log     sessionLog
+		version uint64
+	}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newPoolTransport(config *Config.DbConfig, poolConfig *Config.DbPoolConfig) (*poolTransport, error) {
+	pool := &poolTransport{config: config}
+	pool.opened = sync.NewCond(&pool.lock)
+	if poolConfig != nil {
+		pool.poolConfig = *poolConfig
+	}
+	if pool.poolConfig.MinConnections <= 0 {
+		pool.poolConfig.MinConnections = 1
+	}
+	if pool.poolConfig.MaxConnections < pool.poolConfig.MinConnections {
+		pool.poolConfig.MaxConnections = pool.poolConfig.MinConnections
+	}
+
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
+
+	for i := 0; i < pool.poolConfig.MinConnections; i++ {
+		if _, err := pool.open(pool.ctx); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	go pool.maintain()
+
+	return pool, nil
+}
+
+// Send routes the request to the least loaded connection
+// Requests which change the session are sent to every connection
+func (pool *poolTransport) Send(ctx context.Context, id string, method string, params []any) (*RawResponse, error) {
+	if sessionMethods[method] {
+		return pool.broadcast(ctx, id, method, params)
+	}
+
+	conn, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(conn)
+
+	result, err := conn.ws.Send(ctx, id, method, params)
+	if err != nil || method != "signup" {
+		return result, err
+	}
+
+	// We only want to create the user once, the rest of the connections can use the token
+	if token, ok := result.ResultString(); ok && token != "" {
+		if _, err := pool.broadcast(ctx, id, "authenticate", []any{token}); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Live starts a live query on the least loaded connection, the connection is kept open until the stream is closed
+func (pool *poolTransport) Live(ctx context.Context, id string, method string, params []any) (*internal.LiveStream, error) {
+	conn, err := pool.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.release(conn)
+
+	stream, err := conn.ws.Live(ctx, id, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.lock.Lock()
+	conn.live++
+	pool.lock.Unlock()
+
+	stream.OnClose(func() {
+		pool.lock.Lock()
+		conn.live--
+		pool.lock.Unlock()
+	})
+
+	return stream, nil
+}
+
+func (pool *poolTransport) Close() error {
+	pool.lock.Lock()
+	if pool.closed {
+		pool.lock.Unlock()
+		return nil
+	}
+	pool.closed = true
+	conns := pool.conns
+	pool.conns = nil
+	pool.opened.Broadcast()
+	pool.lock.Unlock()
+
+	pool.cancel()
+
+	var err error
+	for _, conn := range conns {
+		if closeErr := conn.ws.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// --------------------------------------------------
+
+// open dials a new connection and adds it to the pool
+// The session lock isn't held while dialing, so broadcasts aren't held up by it. When the session changed
+// in the meantime the new connection missed that change, so it's closed and we dial again
+func (pool *poolTransport) open(ctx context.Context) (*pooledConnection, error) {
+	for attempt := 1; ; attempt++ {
+		pool.session.lock.Lock()
+		version := pool.session.version
+		changes := pool.session.log.all()
+		pool.session.lock.Unlock()
+
+		conn, err := pool.dial(ctx, changes)
+		if err != nil {
+			return nil, err
+		}
+
+		pool.session.lock.Lock()
+		if pool.session.version != version {
+			pool.session.lock.Unlock()
+			conn.ws.Close()
+			if attempt == maxOpenAttempts {
+				return nil, errSessionChanged
+			}
+			continue
+		}
+
+		pool.lock.Lock()
+		closed := pool.closed
+		if !closed {
+			pool.conns = append(pool.conns, conn)
+		}
+		pool.lock.Unlock()
+		pool.session.lock.Unlock()
+
+		if closed {
+			conn.ws.Close()
+			return nil, ErrPoolClosed
+		}
+
+		return conn, nil
+	}
+}
+
+// openReserved is open, for a connection which was already counted in opening
+func (pool *poolTransport) openReserved(ctx context.Context) (*pooledConnection, error) {
+	conn, err := pool.open(ctx)
+
+	pool.lock.Lock()
+	pool.opening--
+	pool.opened.Broadcast()
+	pool.lock.Unlock()
+
+	return conn, err
+}
+
+// dial opens a new connection and brings it into the session state described by changes
+func (pool *poolTransport) dial(ctx context.Context, changes []sessionChange) (*pooledConnection, error) {
+	ws, err := internal.NewWebsocket(pool.config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		if _, err := ws.Send(ctx, xid(), change.method, change.params); err != nil {
+			ws.Close()
+			return nil, err
+		}
+		change.apply(ws)
+	}
+
+	return &pooledConnection{ws: ws, lastUsed: time.Now()}, nil
+}
+
+// broadcast sends the request to every connection, returning the first response
+func (pool *poolTransport) broadcast(ctx context.Context, id string, method string, params []any) (*RawResponse, error) {
+	pool.session.lock.Lock()
+	defer pool.session.lock.Unlock()
+
+	conns := pool.connections()
+	if len(conns) == 0 {
+		return nil, ErrPoolClosed
+	}
+
+	var result *RawResponse
+	var applied, failed []*pooledConnection
+	for i, conn := range conns {
+		requestId := id
+		if i > 0 {
+			requestId = xid()
+		}
+
+		response, err := conn.ws.Send(ctx, requestId, method, params)
+		if err != nil {
+			// nothing has changed yet, so every connection is still in the same state
+			if result == nil {
+				return nil, err
+			}
+			failed = append(failed, conn)
+			continue
+		}
+		if result == nil {
+			result = response
+		}
+		applied = append(applied, conn)
+	}
+
+	// record the change, so connections opened later and reconnecting connections can replay it
+	if change, ok := newSessionChange(method, params, result); ok {
+		change.apply(&pool.session.log)
+		pool.session.version++
+		for _, conn := range applied {
+			change.apply(conn.ws)
+		}
+	}
+
+	// the connections which missed the change are out of step with the rest, they're replaced
+	// by new connections which replay the whole session
+	if len(failed) > 0 {
+		for _, conn := range failed {
+			pool.remove(conn)
+		}
+		go pool.fill()
+	}
+
+	return result, nil
+}
+
+// acquire picks the connection with the least amount of requests in-flight
+// When every connection is busy and we're below MaxConnections, a new connection is opened
+func (pool *poolTransport) acquire(ctx context.Context) (*pooledConnection, error) {
+	pool.lock.Lock()
+	for {
+		if pool.closed {
+			pool.lock.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		pool.removeClosed()
+
+		least := pool.leastLoaded()
+		canGrow := len(pool.conns)+pool.opening < pool.poolConfig.MaxConnections
+		if least != nil && (least.inflight == 0 || !canGrow) {
+			least.inflight++
+			pool.lock.Unlock()
+			return least, nil
+		}
+		if canGrow {
+			break
+		}
+
+		// every connection we're allowed is still being opened
+		pool.opened.Wait()
+	}
+	pool.opening++
+	pool.lock.Unlock()
+
+	conn, err := pool.openReserved(ctx)
+
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if err != nil {
+		// We can still fall back to one of the existing connections
+		least := pool.leastLoaded()
+		if least == nil || errors.Is(err, ErrPoolClosed) {
+			return nil, err
+		}
+		least.inflight++
+		return least, nil
+	}
+
+	conn.inflight++
+
+	return conn, nil
+}
+
+// leastLoaded returns the connection with the least requests in-flight, the caller must hold the lock
+func (pool *poolTransport) leastLoaded() *pooledConnection {
+	var least *pooledConnection
+	for _, conn := range pool.conns {
+		if least == nil || conn.inflight < least.inflight {
+			least = conn
+		}
+	}
+
+	return least
+}
+
+func (pool *poolTransport) release(conn *pooledConnection) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	conn.inflight--
+	conn.lastUsed = time.Now()
+}
+
+// connections returns a copy of the current connections
+func (pool *poolTransport) connections() []*pooledConnection {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	conns := make([]*pooledConnection, len(pool.conns))
+	copy(conns, pool.conns)
+
+	return conns
+}
+
+// removeClosed drops connections which have closed themselves, the caller must hold the lock
+func (pool *poolTransport) removeClosed() {
+	conns := pool.conns[:0]
+	for _, conn := range pool.conns {
+		if !conn.ws.Closed() {
+			conns = append(conns, conn)
+		}
+	}
+	pool.conns = conns
+}
+
+// maintain runs the health checks and idle reaping, then tops the pool back up to MinConnections
+func (pool *poolTransport) maintain() {
+	interval := pool.poolConfig.HealthCheckInterval
+	if interval <= 0 || (pool.poolConfig.IdleTimeout > 0 && pool.poolConfig.IdleTimeout < interval) {
+		interval = pool.poolConfig.IdleTimeout
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastHealthCheck := time.Now()
+
+	for {
+		select {
+		case <-pool.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if pool.poolConfig.HealthCheckInterval > 0 && time.Since(lastHealthCheck) >= pool.poolConfig.HealthCheckInterval {
+			pool.healthCheck()
+			lastHealthCheck = time.Now()
+		}
+
+		if pool.poolConfig.IdleTimeout > 0 {
+			pool.reapIdle()
+		}
+
+		pool.fill()
+	}
+}
+
+// healthCheck pings every connection, closing the ones which don't respond
+func (pool *poolTransport) healthCheck() {
+	timeout := pool.poolConfig.HealthCheckInterval
+	if pool.config.Timeouts != nil && pool.config.Timeouts.Timeout > 0 && pool.config.Timeouts.Timeout < timeout {
+		timeout = pool.config.Timeouts.Timeout
+	}
+
+	for _, conn := range pool.connections() {
+		ctx, cancel := context.WithTimeout(pool.ctx, timeout)
+		_, err := conn.ws.Send(ctx, xid(), "ping", nil)
+		cancel()
+
+		if err != nil && pool.ctx.Err() == nil {
+			pool.remove(conn)
+		}
+	}
+}
+
+// reapIdle closes connections above MinConnections which haven't been used within the IdleTimeout
+func (pool *poolTransport) reapIdle() {
+	pool.lock.Lock()
+
+	var idle []*pooledConnection
+	conns := pool.conns[:0]
+	for _, conn := range pool.conns {
+		reapable := len(pool.conns)-len(idle) > pool.poolConfig.MinConnections &&
+			conn.inflight == 0 &&
+			conn.live == 0 &&
+			time.Since(conn.lastUsed) >= pool.poolConfig.IdleTimeout
+
+		if reapable {
+			idle = append(idle, conn)
+			continue
+		}
+		conns = append(conns, conn)
+	}
+	pool.conns = conns
+
+	pool.lock.Unlock()
+
+	for _, conn := range idle {
+		conn.ws.Close()
+	}
+}
+
+// fill opens connections until we're back at MinConnections
+func (pool *poolTransport) fill() {
+	for {
+		pool.lock.Lock()
+		pool.removeClosed()
+		missing := !pool.closed && len(pool.conns)+pool.opening < pool.poolConfig.MinConnections
+		if missing {
+			pool.opening++
+		}
+		pool.lock.Unlock()
+
+		if !missing {
+			return
+		}
+
+		if _, err := pool.openReserved(pool.ctx); err != nil {
+			return
+		}
+	}
+}
+
+func (pool *poolTransport) remove(conn *pooledConnection) {
+	pool.lock.Lock()
+	for i, c := range pool.conns {
+		if c == conn {
+			pool.conns = append(pool.conns[:i], pool.conns[i+1:]...)
+			break
+		}
+	}
+	pool.lock.Unlock()
+
+	conn.ws.Close()
+}
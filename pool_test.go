@@ -0,0 +1,207 @@
+package surrealdb_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+func TestPool_ConnectionsShareTheSession(t *testing.T) {
+	mock := newMockServer(t, nil)
+
+	config := mock.config()
+	config.Username = "root"
+	config.Namespace = "test"
+	config.Database = "test"
+	config.AutoLogin = true
+	config.AutoUse = true
+
+	pool, err := surrealdb.NewPool(config, &Config.DbPoolConfig{MinConnections: 2, MaxConnections: 3})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	require.Equal(t, 2, pool.Size())
+	require.Equal(t, []string{"signin", "use"}, mock.methods(0))
+	require.Equal(t, []string{"signin", "use"}, mock.methods(1))
+
+	_, err = pool.Let("tenant", "acme")
+	require.NoError(t, err)
+	require.Equal(t, []string{"signin", "use", "let"}, mock.methods(0))
+	require.Equal(t, []string{"signin", "use", "let"}, mock.methods(1))
+}
+
+func TestPool_GrowsWhenBusyAndReapsIdleConnections(t *testing.T) {
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "query" {
+			started <- struct{}{}
+			<-release
+		}
+		return []any{}, ""
+	})
+
+	pool, err := surrealdb.NewPool(mock.config(), &Config.DbPoolConfig{
+		MinConnections: 1,
+		MaxConnections: 3,
+		IdleTimeout:    50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Use("test", "test")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Query("SELECT * FROM user", map[string]any{})
+			require.NoError(t, err)
+		}()
+		// make sure the previous request is in-flight before sending the next
+		<-started
+	}
+
+	require.Equal(t, 3, pool.Size())
+	// new connections are brought into the same state before being used
+	require.Equal(t, []string{"use", "query"}, mock.methods(2))
+
+	close(release)
+	wg.Wait()
+
+	waitFor(t, time.Second, func() bool { return pool.Size() == 1 })
+}
+
+func TestPool_ConcurrentRequestsDoNotGrowPastMaxConnections(t *testing.T) {
+	started := make(chan struct{}, 20)
+	release := make(chan struct{})
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "query" {
+			started <- struct{}{}
+			<-release
+		}
+		return []any{}, ""
+	})
+
+	pool, err := surrealdb.NewPool(mock.config(), &Config.DbPoolConfig{MinConnections: 1, MaxConnections: 2})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// the first connection is busy, so every request after it wants to open a connection
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := pool.Query("SELECT * FROM user", map[string]any{})
+		require.NoError(t, err)
+	}()
+	<-started
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Query("SELECT * FROM user", map[string]any{})
+			require.NoError(t, err)
+		}()
+	}
+
+	// the second connection takes one of them, the rest wait behind the two in-flight queries
+	<-started
+	require.Equal(t, 2, pool.Size())
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, 2, pool.Size())
+	mock.lock.Lock()
+	require.Len(t, mock.conns, 2)
+	mock.lock.Unlock()
+}
+
+func TestPool_ReplacesConnectionsWhichMissASessionChange(t *testing.T) {
+	var lock sync.Mutex
+	lets := 0
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "let" {
+			lock.Lock()
+			defer lock.Unlock()
+			// the change is broadcast to the connections in order, the second one rejects it
+			if lets++; lets == 2 {
+				return nil, "There was a problem with the database"
+			}
+		}
+		return nil, ""
+	})
+
+	pool, err := surrealdb.NewPool(mock.config(), &Config.DbPoolConfig{MinConnections: 3, MaxConnections: 3})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Let("tenant", "acme")
+	require.NoError(t, err)
+
+	// the connection which missed it is swapped for one which replays the session
+	mock.waitForConnection(3)
+	waitFor(t, time.Second, func() bool { return pool.Size() == 3 })
+	require.Equal(t, []string{"let"}, mock.methods(3))
+}
+
+func TestPool_OpeningConnectionsDoesNotHoldUpSessionChanges(t *testing.T) {
+	var lock sync.Mutex
+	lets := 0
+	releaseReplay := make(chan struct{})
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method != "let" {
+			return nil, ""
+		}
+
+		lock.Lock()
+		lets++
+		n := lets
+		lock.Unlock()
+
+		switch n {
+		case 2:
+			// the second connection rejects the change, so it's replaced
+			return nil, "There was a problem with the database"
+		case 3:
+			// and its replacement is slow to replay the session
+			<-releaseReplay
+		}
+		return nil, ""
+	})
+
+	pool, err := surrealdb.NewPool(mock.config(), &Config.DbPoolConfig{MinConnections: 2, MaxConnections: 2})
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Let("tenant", "acme")
+	require.NoError(t, err)
+	mock.waitForConnection(2)
+
+	// the replacement is still replaying, a session change doesn't wait for it
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.Use("test", "test")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the session change waited for a connection to be opened")
+	}
+
+	// the replacement missed the change, so it's dialed again
+	close(releaseReplay)
+	mock.waitForConnection(3)
+	waitFor(t, time.Second, func() bool { return pool.Size() == 2 })
+	require.Equal(t, []string{"let", "use"}, mock.methods(3))
+}
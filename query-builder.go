@@ -1,6 +1,7 @@
 package surrealdb
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -60,6 +61,9 @@ type QueryBuilder[T any] struct {
 
 	grammarBuilder *QueryGrammarBuilder[T]
 
+	// the db the query is executed with, when nil the default connection is used
+	db *DB
+
 	resolver *ResolvedQuery[T]
 }
 
@@ -75,6 +79,12 @@ func NewBuilder[T any](table ...string) *QueryBuilder[T] {
 	return builder
 }
 
+// On binds the builder to a db, so it's executed with it instead of the default connection
+func (qb *QueryBuilder[T]) On(db *DB) *QueryBuilder[T] {
+	qb.db = db
+	return qb
+}
+
 // From sets the table to query from(when only using one table)
 func (qb *QueryBuilder[T]) From(table string) *QueryBuilder[T] {
 	qb.table = []string{table}
@@ -186,7 +196,12 @@ func (qb *QueryBuilder[T]) GetParams() map[string]any {
 }
 
 func (qb *QueryBuilder[T]) Execute() *ResolvedQuery[T] {
-	resolved := Query[T](qb.GetQuery(), qb.GetParams())
+	return qb.ExecuteCtx(context.Background())
+}
+
+// ExecuteCtx is the same as Execute, but the request will be cancelled when ctx is done
+func (qb *QueryBuilder[T]) ExecuteCtx(ctx context.Context) *ResolvedQuery[T] {
+	resolved := QueryOnCtx[T](ctx, qb.db, qb.GetQuery(), qb.GetParams())
 
 	qb.resolver = resolved
 
@@ -194,13 +209,21 @@ func (qb *QueryBuilder[T]) Execute() *ResolvedQuery[T] {
 }
 
 func (qb *QueryBuilder[T]) First() *T {
+	return qb.FirstCtx(context.Background())
+}
+
+func (qb *QueryBuilder[T]) FirstCtx(ctx context.Context) *T {
 	qb.Limit(1)
 
-	return qb.Execute().First()
+	return qb.ExecuteCtx(ctx).First()
 }
 
 func (qb *QueryBuilder[T]) Get() []T {
-	return qb.Execute().All()
+	return qb.GetCtx(context.Background())
+}
+
+func (qb *QueryBuilder[T]) GetCtx(ctx context.Context) []T {
+	return qb.ExecuteCtx(ctx).All()
 }
 
 func (qb *QueryBuilder[T]) HasError() bool {
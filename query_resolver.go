@@ -1,5 +1,7 @@
 package surrealdb
 
+import "context"
+
 type QueryResolver[T any] struct {
 	err    error
 	query  string
@@ -16,95 +18,234 @@ func createResolver[T any](query string, params any) *QueryResolver[T] {
 }
 
 type QueryConfig struct {
+	// The db to send the query with, when nil the default connection is used
 	Db     *DB
 	Query  string
 	Params any
+	// The context used for the request, when nil context.Background() is used
+	// The configured timeout is still applied on top of this context
+	Ctx context.Context
 }
 
 // Query creates a new query resolver
 // Automatically uses the global db instance, ctx and uses ctx timeouts if configured
 func Query[T any](query string, params ...map[string]any) *ResolvedQuery[T] {
+	return QueryOnCtx[T](context.Background(), nil, query, params...)
+}
+
+// QueryCtx is the same as Query, but the request will be cancelled when ctx is done
+func QueryCtx[T any](ctx context.Context, query string, params ...map[string]any) *ResolvedQuery[T] {
+	return QueryOnCtx[T](ctx, nil, query, params...)
+}
+
+// QueryOn is the same as Query, but uses the given db instead of the default connection
+func QueryOn[T any](db *DB, query string, params ...map[string]any) *ResolvedQuery[T] {
+	return QueryOnCtx[T](context.Background(), db, query, params...)
+}
+
+// QueryOnCtx is the same as QueryOn, but the request will be cancelled when ctx is done
+func QueryOnCtx[T any](ctx context.Context, db *DB, query string, params ...map[string]any) *ResolvedQuery[T] {
 	if len(params) == 0 {
 		// Ensure there's always a default, surreal doesn't like it missing
 		params = append(params, map[string]any{})
 	}
 
 	var config = QueryConfig{
-		Db:     Connection,
+		Db:     db,
 		Params: params[0],
 		Query:  query,
+		Ctx:    ctx,
 	}
 
-	// if dbConfig.Timeouts != nil && dbConfig.Timeouts.Timeout > 0 {
-	// 	ctx, cancel := context.WithTimeout(dbConfig.Ctx, dbConfig.Timeouts.Timeout*time.Millisecond)
-	// 	defer cancel()
-	// 	config.Ctx = ctx
-	// }
-
 	return QueryWithConfig[T](config)
 }
 
 // QueryWithConfig creates a new query resolver
-// Uses a specific db instance and ctx, does not use auto ctx timeouts
+// Uses a specific db instance(or the default connection when nil) and ctx, does not use auto ctx timeouts
 func QueryWithConfig[T any](config QueryConfig) *ResolvedQuery[T] {
-	return createResolver[T](config.Query, config.Params).runQuery(config.Db)
+	ctx := config.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return createResolver[T](config.Query, config.Params).runQuery(ctx, config.Db)
 }
 
-func (resolver *QueryResolver[T]) runQuery(db *DB) *ResolvedQuery[T] {
-	result, err := db.send("query", resolver.query, resolver.params)
+// runQuery sends the query, when it can't be sent the error is returned through the resolver, see Must for panicking instead
+func (resolver *QueryResolver[T]) runQuery(ctx context.Context, db *DB) *ResolvedQuery[T] {
+	db, err := orDefault(db)
 	if err != nil {
-		panic(err)
+		return &ResolvedQuery[T]{err: err, results: []ResultQuery[T]{}}
 	}
 
-	return NewResolvedQuery[T](result)
+	result, err := db.send(ctx, "query", resolver.query, resolver.params)
+	if err != nil {
+		return &ResolvedQuery[T]{err: err, results: []ResultQuery[T]{}}
+	}
+
+	resolved := newResolvedQuery[T](result, resolver.query)
+	// everything we need has been decoded, so the buffer the response was read into can be reused
+	db.release(result)
+
+	return resolved
 }
 
 // Select this will select one or many documents
 // It is the same as: https://surrealdb.com/docs/integration/http#select-all
 func Select[T any](what string) *ResolvedCrudResult[T] {
-	return createResolver[T](what, map[string]any{}).runCrud(Connection, "select")
+	return SelectOnCtx[T](context.Background(), nil, what)
+}
+
+// SelectCtx is the same as Select, but the request will be cancelled when ctx is done
+func SelectCtx[T any](ctx context.Context, what string) *ResolvedCrudResult[T] {
+	return SelectOnCtx[T](ctx, nil, what)
+}
+
+// SelectOn is the same as Select, but uses the given db instead of the default connection
+func SelectOn[T any](db *DB, what string) *ResolvedCrudResult[T] {
+	return SelectOnCtx[T](context.Background(), db, what)
+}
+
+// SelectOnCtx is the same as SelectOn, but the request will be cancelled when ctx is done
+func SelectOnCtx[T any](ctx context.Context, db *DB, what string) *ResolvedCrudResult[T] {
+	return createResolver[T](what, map[string]any{}).runCrud(ctx, db, "select")
 }
 
 // Create This will create a new document
 // It is the same as: https://surrealdb.com/docs/integration/http#create-all
 func Create[T any, DType any | map[string]any](what string, data DType) ResolvedCreateResult[T] {
-	return createResolver[T](what, data).runCrud(Connection, "create")
+	return CreateOnCtx[T](context.Background(), nil, what, data)
+}
+
+// CreateCtx is the same as Create, but the request will be cancelled when ctx is done
+func CreateCtx[T any, DType any | map[string]any](ctx context.Context, what string, data DType) ResolvedCreateResult[T] {
+	return CreateOnCtx[T](ctx, nil, what, data)
+}
+
+// CreateOn is the same as Create, but uses the given db instead of the default connection
+func CreateOn[T any, DType any | map[string]any](db *DB, what string, data DType) ResolvedCreateResult[T] {
+	return CreateOnCtx[T](context.Background(), db, what, data)
+}
+
+// CreateOnCtx is the same as CreateOn, but the request will be cancelled when ctx is done
+func CreateOnCtx[T any, DType any | map[string]any](ctx context.Context, db *DB, what string, data DType) ResolvedCreateResult[T] {
+	return createResolver[T](what, data).runCrud(ctx, db, "create")
 }
 
 // Update This will apply a "replace" change to the document
 // It is the same as: https://surrealdb.com/docs/integration/http#update-one
 func Update[T any, DType any | map[string]any](what string, data DType) ResolvedUpdateResult[T] {
-	return createResolver[T](what, data).runCrud(Connection, "update")
+	return UpdateOnCtx[T](context.Background(), nil, what, data)
+}
+
+// UpdateCtx is the same as Update, but the request will be cancelled when ctx is done
+func UpdateCtx[T any, DType any | map[string]any](ctx context.Context, what string, data DType) ResolvedUpdateResult[T] {
+	return UpdateOnCtx[T](ctx, nil, what, data)
+}
+
+// UpdateOn is the same as Update, but uses the given db instead of the default connection
+func UpdateOn[T any, DType any | map[string]any](db *DB, what string, data DType) ResolvedUpdateResult[T] {
+	return UpdateOnCtx[T](context.Background(), db, what, data)
+}
+
+// UpdateOnCtx is the same as UpdateOn, but the request will be cancelled when ctx is done
+func UpdateOnCtx[T any, DType any | map[string]any](ctx context.Context, db *DB, what string, data DType) ResolvedUpdateResult[T] {
+	return createResolver[T](what, data).runCrud(ctx, db, "update")
 }
 
 // Change This will apply a "merge" change to the document
 // It is the same as: https://surrealdb.com/docs/integration/http#modify-one
 func Change[T any, DType any | map[string]any](what string, data DType) ResolvedUpdateResult[T] {
-	return createResolver[T](what, data).runCrud(Connection, "change")
+	return ChangeOnCtx[T](context.Background(), nil, what, data)
+}
+
+// ChangeCtx is the same as Change, but the request will be cancelled when ctx is done
+func ChangeCtx[T any, DType any | map[string]any](ctx context.Context, what string, data DType) ResolvedUpdateResult[T] {
+	return ChangeOnCtx[T](ctx, nil, what, data)
+}
+
+// ChangeOn is the same as Change, but uses the given db instead of the default connection
+func ChangeOn[T any, DType any | map[string]any](db *DB, what string, data DType) ResolvedUpdateResult[T] {
+	return ChangeOnCtx[T](context.Background(), db, what, data)
+}
+
+// ChangeOnCtx is the same as ChangeOn, but the request will be cancelled when ctx is done
+func ChangeOnCtx[T any, DType any | map[string]any](ctx context.Context, db *DB, what string, data DType) ResolvedUpdateResult[T] {
+	return createResolver[T](what, data).runCrud(ctx, db, "change")
 }
 
 // Modify applies a JSONPatch to the document
 func Modify(what string, data []Patch) *ResolvedModifyResult {
-	return createResolver[any](what, data).runModify(Connection)
+	return ModifyOnCtx(context.Background(), nil, what, data)
+}
+
+// ModifyCtx is the same as Modify, but the request will be cancelled when ctx is done
+func ModifyCtx(ctx context.Context, what string, data []Patch) *ResolvedModifyResult {
+	return ModifyOnCtx(ctx, nil, what, data)
+}
+
+// ModifyOn is the same as Modify, but uses the given db instead of the default connection
+func ModifyOn(db *DB, what string, data []Patch) *ResolvedModifyResult {
+	return ModifyOnCtx(context.Background(), db, what, data)
+}
+
+// ModifyOnCtx is the same as ModifyOn, but the request will be cancelled when ctx is done
+func ModifyOnCtx(ctx context.Context, db *DB, what string, data []Patch) *ResolvedModifyResult {
+	return createResolver[any](what, data).runModify(ctx, db)
 }
 
 // Delete deletes a document or all documents
 func Delete[T any](what string) ResolvedUpdateResult[T] {
-	return createResolver[T](what, map[string]any{}).runCrud(Connection, "delete")
+	return DeleteOnCtx[T](context.Background(), nil, what)
+}
+
+// DeleteCtx is the same as Delete, but the request will be cancelled when ctx is done
+func DeleteCtx[T any](ctx context.Context, what string) ResolvedUpdateResult[T] {
+	return DeleteOnCtx[T](ctx, nil, what)
+}
+
+// DeleteOn is the same as Delete, but uses the given db instead of the default connection
+func DeleteOn[T any](db *DB, what string) ResolvedUpdateResult[T] {
+	return DeleteOnCtx[T](context.Background(), db, what)
+}
+
+// DeleteOnCtx is the same as DeleteOn, but the request will be cancelled when ctx is done
+func DeleteOnCtx[T any](ctx context.Context, db *DB, what string) ResolvedUpdateResult[T] {
+	return createResolver[T](what, map[string]any{}).runCrud(ctx, db, "delete")
 }
 
-func (resolver *QueryResolver[T]) runCrud(db *DB, method string) *ResolvedCrudResult[T] {
-	result, err := db.send(method, resolver.query, resolver.params)
+func (resolver *QueryResolver[T]) runCrud(ctx context.Context, db *DB, method string) *ResolvedCrudResult[T] {
+	db, err := orDefault(db)
+	if err != nil {
+		return &ResolvedCrudResult[T]{err: err}
+	}
+
+	result, err := db.send(ctx, method, resolver.query, resolver.params)
 	if err != nil {
-		panic(err)
+		return &ResolvedCrudResult[T]{err: err}
 	}
-	return NewResolvedCrudResult[T](result)
+
+	resolved := NewResolvedCrudResult[T](result)
+	// everything we need has been decoded, so the buffer the response was read into can be reused
+	db.release(result)
+
+	return resolved
 }
 
-func (resolver *QueryResolver[T]) runModify(db *DB) *ResolvedModifyResult {
-	result, err := db.send("modify", resolver.query, resolver.params)
+func (resolver *QueryResolver[T]) runModify(ctx context.Context, db *DB) *ResolvedModifyResult {
+	db, err := orDefault(db)
 	if err != nil {
-		panic(err)
+		return &ResolvedModifyResult{err: err}
 	}
-	return NewResolvedModifyResult(result)
+
+	result, err := db.send(ctx, "modify", resolver.query, resolver.params)
+	if err != nil {
+		return &ResolvedModifyResult{err: err}
+	}
+
+	resolved := NewResolvedModifyResult(result)
+	// everything we need has been decoded, so the buffer the response was read into can be reused
+	db.release(result)
+
+	return resolved
 }
@@ -2,11 +2,9 @@ package surrealdb
 
 import (
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/buger/jsonparser"
-	"github.com/goccy/go-json"
 	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
 )
 
@@ -24,12 +22,20 @@ type ResolvedQuery[T any] struct {
 
 	response *internal.RPCRawResponse
 
+	// query is what was sent, used for the excerpts of statement errors
+	query string
+
 	results []ResultQuery[T]
 }
 
 func NewResolvedQuery[T any](response *internal.RPCRawResponse) *ResolvedQuery[T] {
+	return newResolvedQuery[T](response, "")
+}
+
+func newResolvedQuery[T any](response *internal.RPCRawResponse, query string) *ResolvedQuery[T] {
 	resolved := &ResolvedQuery[T]{
 		response: response,
+		query:    query,
 		results:  []ResultQuery[T]{},
 	}
 
@@ -53,12 +59,47 @@ func (resolver *ResolvedQuery[T]) process() {
 		return
 	}
 
-	err := json.Unmarshal(rpcResult.Result, &resolver.results)
-	if err != nil {
+	// statements are decoded one by one, the result of a failed one is its error message, which won't decode into []T
+	index := 0
+	codec := resolver.response.Codec()
+	_, err := jsonparser.ArrayEach(rpcResult.Result, func(value []byte, _ jsonparser.ValueType, _ int, _ error) {
+		statement := index
+		index++
+		if resolver.err != nil {
+			return
+		}
+
+		status, _ := jsonparser.GetString(value, "status")
+		if status == "OK" {
+			var result ResultQuery[T]
+			if err := codec.Unmarshal(value, &result); err != nil {
+				resolver.err = err
+				return
+			}
+			resolver.results = append(resolver.results, result)
+			return
+		}
+
+		message, _ := jsonparser.GetString(value, "detail")
+		if message == "" {
+			message, _ = jsonparser.GetString(value, "result")
+		}
+		timeTaken, _ := jsonparser.GetString(value, "time")
+
+		resolver.results = append(resolver.results, ResultQuery[T]{
+			Result: []T{},
+			Status: status,
+			Time:   timeTaken,
+			Err:    internal.NewQueryStatementError(statement, status, message, resolver.query),
+		})
+	})
+	if err != nil && resolver.err == nil {
 		resolver.err = err
 	}
 }
 
+// HasError Check if the query couldn't be sent, or its response couldn't be decoded
+// A statement failing isn't an error of the query, see FirstError and AllAreSuccessful for those
 func (resolver *ResolvedQuery[T]) HasError() bool {
 	return resolver.err != nil
 }
@@ -84,6 +125,29 @@ func (resolver *ResolvedQuery[T]) AllAreSuccessful() bool {
 	return true
 }
 
+// FirstError Get the error of the first statement which failed, nil when they were all successful
+func (resolver *ResolvedQuery[T]) FirstError() *StatementError {
+	for i := range resolver.results {
+		if resolver.results[i].Err != nil {
+			return resolver.results[i].Err
+		}
+	}
+
+	return nil
+}
+
+// Errors Get the errors of all the statements which failed
+func (resolver *ResolvedQuery[T]) Errors() []*StatementError {
+	var errs []*StatementError
+	for i := range resolver.results {
+		if resolver.results[i].Err != nil {
+			errs = append(errs, resolver.results[i].Err)
+		}
+	}
+
+	return errs
+}
+
 // TotalTimeTaken Get the total time taken for all the queries to complete
 func (resolver *ResolvedQuery[T]) TotalTimeTaken() time.Duration {
 	timeTaken := time.Duration(0).Nanoseconds()
@@ -93,9 +157,9 @@ func (resolver *ResolvedQuery[T]) TotalTimeTaken() time.Duration {
 	}
 
 	for _, result := range resolver.results {
+		// a time we can't parse is left out of the total
 		t, err := time.ParseDuration(result.Time)
 		if err != nil {
-			fmt.Println("Error parsing time:", err)
 			continue
 		}
 		timeTaken += t.Nanoseconds()
@@ -219,7 +283,7 @@ func (resolver *ResolvedCrudResult[T]) process() {
 		return
 	}
 
-	err := json.Unmarshal(rpcResult.Result, &resolver.results)
+	err := resolver.response.DecodeResult(&resolver.results)
 	if err != nil {
 		resolver.err = err
 	}
@@ -306,7 +370,7 @@ func (resolver *ResolvedModifyResult) process() {
 		return
 	}
 
-	err := json.Unmarshal(rpcResult.Result, &resolver.results)
+	err := resolver.response.DecodeResult(&resolver.results)
 	if err != nil {
 		resolver.err = err
 	}
@@ -0,0 +1,87 @@
+package surrealdb
+
+// The query and crud functions never panic, errors(including the request failing to send) are reported
+// through HasError/Error on the result. The Must* functions panic with the error instead, for setup code
+// and scripts where there's nothing better to do with it
+
+// Must panics when the result has an error, otherwise it returns the result
+// It works with the result of any of the query and crud functions, for example:
+//
+//	users := surrealdb.Must(surrealdb.QueryOnCtx[User](ctx, db, "SELECT * FROM user")).All()
+func Must[R interface{ Error() error }](result R) R {
+	if err := result.Error(); err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// MustQuery is the same as Query, but panics when it fails
+func MustQuery[T any](query string, params ...map[string]any) *ResolvedQuery[T] {
+	return Must(Query[T](query, params...))
+}
+
+// MustQueryOn is the same as QueryOn, but panics when it fails
+func MustQueryOn[T any](db *DB, query string, params ...map[string]any) *ResolvedQuery[T] {
+	return Must(QueryOn[T](db, query, params...))
+}
+
+// MustSelect is the same as Select, but panics when it fails
+func MustSelect[T any](what string) *ResolvedCrudResult[T] {
+	return Must(Select[T](what))
+}
+
+// MustSelectOn is the same as SelectOn, but panics when it fails
+func MustSelectOn[T any](db *DB, what string) *ResolvedCrudResult[T] {
+	return Must(SelectOn[T](db, what))
+}
+
+// MustCreate is the same as Create, but panics when it fails
+func MustCreate[T any, DType any | map[string]any](what string, data DType) ResolvedCreateResult[T] {
+	return Must(Create[T](what, data))
+}
+
+// MustCreateOn is the same as CreateOn, but panics when it fails
+func MustCreateOn[T any, DType any | map[string]any](db *DB, what string, data DType) ResolvedCreateResult[T] {
+	return Must(CreateOn[T](db, what, data))
+}
+
+// MustUpdate is the same as Update, but panics when it fails
+func MustUpdate[T any, DType any | map[string]any](what string, data DType) ResolvedUpdateResult[T] {
+	return Must(Update[T](what, data))
+}
+
+// MustUpdateOn is the same as UpdateOn, but panics when it fails
+func MustUpdateOn[T any, DType any | map[string]any](db *DB, what string, data DType) ResolvedUpdateResult[T] {
+	return Must(UpdateOn[T](db, what, data))
+}
+
+// MustChange is the same as Change, but panics when it fails
+func MustChange[T any, DType any | map[string]any](what string, data DType) ResolvedUpdateResult[T] {
+	return Must(Change[T](what, data))
+}
+
+// MustChangeOn is the same as ChangeOn, but panics when it fails
+func MustChangeOn[T any, DType any | map[string]any](db *DB, what string, data DType) ResolvedUpdateResult[T] {
+	return Must(ChangeOn[T](db, what, data))
+}
+
+// MustModify is the same as Modify, but panics when it fails
+func MustModify(what string, data []Patch) *ResolvedModifyResult {
+	return Must(Modify(what, data))
+}
+
+// MustModifyOn is the same as ModifyOn, but panics when it fails
+func MustModifyOn(db *DB, what string, data []Patch) *ResolvedModifyResult {
+	return Must(ModifyOn(db, what, data))
+}
+
+// MustDelete is the same as Delete, but panics when it fails
+func MustDelete[T any](what string) ResolvedUpdateResult[T] {
+	return Must(Delete[T](what))
+}
+
+// MustDeleteOn is the same as DeleteOn, but panics when it fails
+func MustDeleteOn[T any](db *DB, what string) ResolvedUpdateResult[T] {
+	return Must(DeleteOn[T](db, what))
+}
@@ -0,0 +1,78 @@
+package surrealdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	"github.com/test-go/testify/require"
+)
+
+func TestResolvers_ReturnSendErrors(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "select" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{}}}, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	selected := surrealdb.SelectOnCtx[liveUser](ctx, db, "user")
+	require.True(t, selected.HasError())
+	require.True(t, errors.Is(selected.Error(), surrealdb.ErrTimeout), selected.Error())
+	require.Nil(t, selected.First())
+
+	require.NoError(t, db.Close())
+
+	query := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user")
+	require.True(t, query.HasError())
+	require.True(t, errors.Is(query.Error(), surrealdb.ErrConnection), query.Error())
+	require.Nil(t, query.First())
+	require.Empty(t, query.Results())
+	require.True(t, query.IsEmpty())
+	require.False(t, query.AllAreSuccessful())
+
+	created := surrealdb.CreateOn[liveUser](db, "user", map[string]any{"username": "bob"})
+	require.True(t, errors.Is(created.Error(), surrealdb.ErrConnectionClosed), created.Error())
+	require.Nil(t, created.Item())
+
+	modified := surrealdb.ModifyOn(db, "user:1", []surrealdb.Patch{{Op: "replace", Path: "/username", Value: "bob"}})
+	require.True(t, modified.HasError())
+	require.Empty(t, modified.First())
+}
+
+func TestResolvers_MustPanics(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob"}}}}, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+
+	user := surrealdb.MustQueryOn[liveUser](db, "SELECT * FROM user").First()
+	require.NotNil(t, user)
+	require.Equal(t, "bob", user.Username)
+
+	require.NoError(t, db.Close())
+
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+		}()
+		surrealdb.MustQueryOn[liveUser](db, "SELECT * FROM user")
+	}()
+	require.Panics(t, func() {
+		surrealdb.Must(surrealdb.SelectOnCtx[liveUser](context.Background(), db, "user"))
+	})
+	require.Panics(t, func() {
+		surrealdb.MustDeleteOn[liveUser](db, "user:1")
+	})
+}
@@ -1,12 +1,15 @@
 package surrealdb_test
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/idevelopthings/surrealdb.go.unofficial"
 	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
 )
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -238,3 +241,68 @@ func Test_QueryResolver_Delete(t *testing.T) {
 	}
 
 }
+
+func TestResolvers_StatementErrors(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if !strings.Contains(string(req.Params[0]), ";") {
+			return []map[string]any{{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob"}}}}, ""
+		}
+		return []map[string]any{
+			{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:1", "username": "bob"}}},
+			{"status": "ERR", "time": "2ms", "result": "Parse error on line 1 at character 0 when parsing 'SELEC * FROM user'"},
+			{"status": "ERR", "time": "1ms", "detail": "You don't have permission to view this table"},
+			{"status": "OK", "time": "1ms", "result": []map[string]any{{"id": "user:2", "username": "alice"}}},
+		}, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	result := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user:1; SELEC * FROM user; SELECT * FROM secret; SELECT * FROM user:2")
+	require.NoError(t, result.Error())
+	require.False(t, result.AllAreSuccessful())
+
+	results := result.Results()
+	require.Len(t, results, 4)
+	require.False(t, results[0].HasError())
+	require.Equal(t, "bob", result.First().Username)
+	require.Equal(t, "alice", results[3].Result[0].Username)
+	require.Empty(t, results[1].Result)
+
+	first := result.FirstError()
+	require.NotNil(t, first)
+	require.Equal(t, 1, first.Index)
+	require.Equal(t, "ERR", first.Status)
+	require.Equal(t, "SELEC * FROM user", first.Excerpt)
+	var parseErr *surrealdb.ParseError
+	require.True(t, errors.As(first, &parseErr))
+	require.True(t, errors.Is(results[2].Err, surrealdb.ErrPermission))
+
+	require.Len(t, result.Errors(), 2)
+	require.Equal(t, 2, result.Errors()[1].Index)
+
+	ok := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user:1")
+	require.Nil(t, ok.FirstError())
+}
+
+func TestResolvers_StatementErrorExcerptFromTheQuery(t *testing.T) {
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		return []map[string]any{
+			{"status": "OK", "time": "1ms", "result": []map[string]any{}},
+			{"status": "ERR", "time": "1ms", "result": "Parse error on line 2 at character 7"},
+		}, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	result := surrealdb.QueryOn[liveUser](db, "SELECT * FROM user;\n  SELECT * FORM user;")
+	require.NoError(t, result.Error())
+
+	first := result.FirstError()
+	require.NotNil(t, first)
+	require.Equal(t, 1, first.Index)
+	require.Equal(t, "SELECT * FORM user;", first.Excerpt)
+}
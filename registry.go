@@ -0,0 +1,101 @@
+package surrealdb
+
+import (
+	"errors"
+	"sync"
+)
+
+// DefaultConnection is the name of the connection used when a DB isn't given explicitly
+const DefaultConnection = "default"
+
+var (
+	// ErrNoConnection is returned when there is no DB to send the request with
+	ErrNoConnection = errors.New("no database connection, create one with surrealdb.New or pass a *DB explicitly")
+)
+
+// registry holds the named connections, see Register and Conn
+var registry = struct {
+	lock  sync.RWMutex
+	conns map[string]*DB
+}{conns: make(map[string]*DB)}
+
+// Register makes db available by name, replacing any connection registered with that name
+// Registering DefaultConnection changes the DB used by the helpers which don't take one(Query, Select etc)
+func Register(name string, db *DB) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	registry.conns[name] = db
+	if name == DefaultConnection {
+		Connection = db
+	}
+}
+
+// Unregister removes the connection registered with name, it doesn't close it
+func Unregister(name string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	delete(registry.conns, name)
+	if name == DefaultConnection {
+		Connection = nil
+	}
+}
+
+// Conn returns the connection registered with name, or nil when there isn't one
+func Conn(name string) *DB {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	if db, ok := registry.conns[name]; ok {
+		return db
+	}
+
+	// Connection may still be assigned directly
+	if name == DefaultConnection {
+		return Connection
+	}
+
+	return nil
+}
+
+// registerDefault registers db as the default connection, unless there already is one
+func registerDefault(db *DB) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	if registry.conns[DefaultConnection] != nil || Connection != nil {
+		return
+	}
+
+	registry.conns[DefaultConnection] = db
+	Connection = db
+}
+
+// unregisterAll removes db from every name it was registered with
+func unregisterAll(db *DB) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	for name, conn := range registry.conns {
+		if conn == db {
+			delete(registry.conns, name)
+		}
+	}
+	if Connection == db {
+		Connection = nil
+	}
+}
+
+// orDefault returns db, or the default connection when it's nil
+func orDefault(db *DB) (*DB, error) {
+	if db != nil {
+		return db, nil
+	}
+
+	if db = Conn(DefaultConnection); db == nil {
+		return nil, ErrNoConnection
+	}
+
+	return db, nil
+}
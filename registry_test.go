@@ -0,0 +1,61 @@
+package surrealdb_test
+
+import (
+	"testing"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	"github.com/test-go/testify/require"
+)
+
+func TestRegistry_NamedConnections(t *testing.T) {
+	mainServer := newMockServer(t, func(req mockRequest) (any, string) {
+		return []any{map[string]any{"status": "OK", "time": "1ms", "result": []any{map[string]any{"username": "main"}}}}, ""
+	})
+	analyticsServer := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method == "select" {
+			return []any{map[string]any{"username": "analytics"}}, ""
+		}
+		return []any{map[string]any{"status": "OK", "time": "1ms", "result": []any{map[string]any{"username": "analytics"}}}}, ""
+	})
+
+	main, err := surrealdb.New(mainServer.config())
+	require.NoError(t, err)
+	defer main.Close()
+
+	analytics, err := surrealdb.New(analyticsServer.config())
+	require.NoError(t, err)
+	defer analytics.Close()
+
+	surrealdb.Register(surrealdb.DefaultConnection, main)
+	surrealdb.Register("analytics", analytics)
+	defer surrealdb.Unregister("analytics")
+
+	require.Equal(t, analytics, surrealdb.Conn("analytics"))
+	require.Nil(t, surrealdb.Conn("missing"))
+
+	user := surrealdb.Query[testUserInformation]("SELECT * FROM user").First()
+	require.Equal(t, "main", user.Username)
+
+	user = surrealdb.QueryOn[testUserInformation](surrealdb.Conn("analytics"), "SELECT * FROM user").First()
+	require.Equal(t, "analytics", user.Username)
+
+	user = surrealdb.SelectOn[testUserInformation](analytics, "user").First()
+	require.Equal(t, "analytics", user.Username)
+
+	user = surrealdb.NewBuilder[testUserInformation]("user").On(analytics).First()
+	require.Equal(t, "analytics", user.Username)
+
+	require.Equal(t, []string{"query"}, mainServer.methods(0))
+	require.Equal(t, []string{"query", "select", "query"}, analyticsServer.methods(0))
+
+	// closing a connection removes it from the registry
+	analytics.Close()
+	require.Nil(t, surrealdb.Conn("analytics"))
+}
+
+func TestRegistry_NoDefaultConnection(t *testing.T) {
+	surrealdb.Unregister(surrealdb.DefaultConnection)
+
+	_, err := surrealdb.LiveQuery[testUserInformation](nil, "user")
+	require.Equal(t, surrealdb.ErrNoConnection, err)
+}
@@ -0,0 +1,102 @@
+package surrealdb
+
+// Keys used for the session state which is replayed after reconnecting
+const (
+	sessionAuth = "auth"
+	sessionUse  = "use"
+	sessionLet  = "let:"
+)
+
+// sessionChange describes how a successful request changed the state of the connection
+type sessionChange struct {
+	key    string
+	method string
+	params []any
+	// when true, the key is removed from the session instead
+	remove bool
+}
+
+// newSessionChange checks if the method changes the session state, returning the change to record
+func newSessionChange(method string, params []any, result *RawResponse) (sessionChange, bool) {
+	switch method {
+	case "use":
+		return sessionChange{key: sessionUse, method: method, params: params}, true
+
+	case "signin", "authenticate":
+		return sessionChange{key: sessionAuth, method: method, params: params}, true
+
+	case "signup":
+		// re-running the signup itself after a reconnect would try to create the user again,
+		// so we store the token it gave us instead
+		if token, ok := result.ResultString(); ok && token != "" {
+			return sessionChange{key: sessionAuth, method: "authenticate", params: []any{token}}, true
+		}
+
+	case "invalidate":
+		return sessionChange{key: sessionAuth, remove: true}, true
+
+	case "let", "unset":
+		if len(params) == 0 {
+			return sessionChange{}, false
+		}
+		key, ok := params[0].(string)
+		if !ok {
+			return sessionChange{}, false
+		}
+		return sessionChange{key: sessionLet + key, method: method, params: params, remove: method == "unset"}, true
+	}
+
+	return sessionChange{}, false
+}
+
+func (change sessionChange) apply(session sessionTransport) {
+	if change.remove {
+		session.RemoveSession(change.key)
+		return
+	}
+
+	session.SetSession(change.key, change.method, change.params)
+}
+
+// sessionLog keeps the session changes in the order they happened, a change to an existing key
+// replaces it and moves it to the end
+type sessionLog struct {
+	keys    []string
+	changes map[string]sessionChange
+}
+
+func (log *sessionLog) SetSession(key string, method string, params []any) {
+	log.RemoveSession(key)
+
+	if log.changes == nil {
+		log.changes = make(map[string]sessionChange)
+	}
+
+	log.keys = append(log.keys, key)
+	log.changes[key] = sessionChange{key: key, method: method, params: params}
+}
+
+func (log *sessionLog) RemoveSession(key string) {
+	if _, ok := log.changes[key]; !ok {
+		return
+	}
+
+	delete(log.changes, key)
+
+	for i, k := range log.keys {
+		if k == key {
+			log.keys = append(log.keys[:i], log.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// all returns the changes in the order they should be replayed
+func (log *sessionLog) all() []sessionChange {
+	changes := make([]sessionChange, 0, len(log.keys))
+	for _, key := range log.keys {
+		changes = append(changes, log.changes[key])
+	}
+
+	return changes
+}
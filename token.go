@@ -0,0 +1,156 @@
+package surrealdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	ErrTokenExpired          = errors.New("token has expired")
+	ErrTokenNotValidYet      = errors.New("token is not valid yet")
+	ErrTokenInvalidSignature = errors.New("token signature is invalid")
+	ErrTokenAlgorithm        = errors.New("token algorithm is not allowed")
+)
+
+// tokenAlgorithms are the signing algorithms we can verify
+var tokenAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// TokenHeader is the header of a jwt
+type TokenHeader struct {
+	Type      string `json:"typ"`
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid,omitempty"`
+}
+
+// Token is a parsed jwt
+type Token struct {
+	Raw       string
+	Header    TokenHeader
+	Claims    TokenData
+	Signature []byte
+}
+
+// TokenParser parses and validates the tokens issued by SurrealDB
+// For example, to accept scope tokens in your own http middleware:
+//
+//	DEFINE TOKEN my_token ON SCOPE account TYPE HS512 VALUE "secret";
+//	parser := surrealdb.TokenParser{Key: []byte("secret"), Algorithms: []string{"HS512"}}
+type TokenParser struct {
+	// Key verifies the signature, it's the VALUE of the DEFINE TOKEN/DEFINE SCOPE statement
+	// When nil, the signature isn't verified
+	Key []byte
+	// Algorithms which are accepted, defaults to HS256, HS384 and HS512
+	Algorithms []string
+	// ClockSkew is the leeway applied to exp and nbf, to allow for clocks which aren't in sync
+	ClockSkew time.Duration
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+// Parse decodes the token, validates exp/nbf and verifies the signature when a Key is set
+// Claims are still returned when the token has expired or isn't valid yet
+func (parser TokenParser) Parse(tokenString string) (*Token, error) {
+	segments := strings.Split(tokenString, ".")
+	if tokenString == "" || len(segments) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	token := &Token{Raw: tokenString}
+
+	header, err := decodeTokenSegment(segments[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := json.Unmarshal(header, &token.Header); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := decodeTokenSegment(segments[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &token.Claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if token.Signature, err = decodeTokenSegment(segments[2]); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !parser.allowed(token.Header.Algorithm) {
+		return token, ErrTokenAlgorithm
+	}
+
+	if parser.Key != nil {
+		mac := hmac.New(tokenAlgorithms[token.Header.Algorithm], parser.Key)
+		mac.Write([]byte(segments[0] + "." + segments[1]))
+
+		if !hmac.Equal(mac.Sum(nil), token.Signature) {
+			return token, ErrTokenInvalidSignature
+		}
+	}
+
+	return token, parser.validateTime(token.Claims)
+}
+
+func (parser TokenParser) allowed(algorithm string) bool {
+	// without a key we don't care how it was signed, unless we've been told otherwise
+	if parser.Key == nil && len(parser.Algorithms) == 0 {
+		return true
+	}
+	if _, ok := tokenAlgorithms[algorithm]; !ok {
+		return false
+	}
+	if len(parser.Algorithms) == 0 {
+		return true
+	}
+
+	for _, allowed := range parser.Algorithms {
+		if allowed == algorithm {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (parser TokenParser) validateTime(claims TokenData) error {
+	now := time.Now()
+	if parser.Now != nil {
+		now = parser.Now()
+	}
+
+	if claims.ExpiresAt != 0 && now.Add(-parser.ClockSkew).After(time.Unix(int64(claims.ExpiresAt), 0)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(parser.ClockSkew).Before(time.Unix(int64(claims.NotBefore), 0)) {
+		return ErrTokenNotValidYet
+	}
+
+	return nil
+}
+
+// decodeTokenSegment decodes a base64url jwt segment, padding is optional
+// Segments using the standard base64 alphabet are still accepted
+func decodeTokenSegment(segment string) ([]byte, error) {
+	segment = strings.TrimRight(segment, "=")
+
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return base64.RawStdEncoding.DecodeString(segment)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,292 @@
+package surrealdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
+
+var (
+	// ErrNoCredentials is returned when the token has to be refreshed, but we don't know how
+	ErrNoCredentials = errors.New("no credentials or refresh callback to re-authenticate with")
+)
+
+const (
+	defaultTokenRefreshLeeway = 30 * time.Second
+	defaultTokenRetryDelay    = 5 * time.Second
+)
+
+// TokenRefreshConfig configures the token manager, see DB.EnableTokenRefresh
+type TokenRefreshConfig struct {
+	// Refresh returns a new token, which is then used to authenticate
+	// When nil, we sign in again with the credentials used for the last Signin/Signup
+	Refresh func(ctx context.Context) (string, error)
+	// How long before the token expires it's refreshed, defaults to 30 seconds
+	Leeway time.Duration
+	// How long to wait before trying again when refreshing failed, defaults to 5 seconds
+	RetryDelay time.Duration
+	// OnRefresh is called after every refresh, with the new authentication result or the error
+	OnRefresh func(result *AuthenticationResult, err error)
+}
+
+// tokenManager re-authenticates before the token expires, or when a request fails because it has
+type tokenManager struct {
+	db     *DB
+	config TokenRefreshConfig
+
+	// refreshing is held whilst re-authenticating, so only one refresh runs at a time
+	refreshing sync.Mutex
+
+	lock sync.Mutex
+	// credentials are the vars of the last signin/signup
+	credentials any
+	timer       *time.Timer
+	// generation is increased whenever we're authenticated again, so concurrent failures only refresh once
+	generation uint64
+	stopped    bool
+}
+
+// EnableTokenRefresh re-authenticates shortly before the token expires, and retries requests once
+// when they fail because of an expired token
+// Credentials are remembered from every signin/signup(including the typed and scope variants, and the ones made
+// before this was called, like AutoLogin), unless config.Refresh is set
+func (db *DB) EnableTokenRefresh(config TokenRefreshConfig) {
+	if config.Leeway <= 0 {
+		config.Leeway = defaultTokenRefreshLeeway
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultTokenRetryDelay
+	}
+
+	db.tokens.lock.Lock()
+	if db.tokens.manager != nil {
+		db.tokens.manager.stop()
+	}
+	manager := &tokenManager{db: db, config: config, credentials: db.tokens.credentials}
+	db.tokens.manager = manager
+	token := db.tokens.token
+	db.tokens.lock.Unlock()
+
+	// we're already signed in, so the refresh is scheduled for the current token
+	if token != "" {
+		manager.authenticated(token)
+	}
+}
+
+// DisableTokenRefresh stops the token manager, the current session is kept
+func (db *DB) DisableTokenRefresh() {
+	db.tokens.lock.Lock()
+	defer db.tokens.lock.Unlock()
+
+	if db.tokens.manager != nil {
+		db.tokens.manager.stop()
+		db.tokens.manager = nil
+	}
+}
+
+func (db *DB) tokenManager() *tokenManager {
+	db.tokens.lock.Lock()
+	defer db.tokens.lock.Unlock()
+
+	return db.tokens.manager
+}
+
+// rememberAuth keeps the credentials and token of the last successful signin/signup/authenticate
+func (db *DB) rememberAuth(method string, params []any, result *RawResponse) {
+	db.tokens.lock.Lock()
+	defer db.tokens.lock.Unlock()
+
+	switch method {
+	case "signin", "signup":
+		if len(params) > 0 {
+			db.tokens.credentials = params[0]
+		}
+		db.tokens.token, _ = result.ResultString()
+
+	case "authenticate":
+		if len(params) > 0 {
+			db.tokens.token, _ = params[0].(string)
+		}
+
+	case "invalidate":
+		db.tokens.credentials = nil
+		db.tokens.token = ""
+	}
+}
+
+// --------------------------------------------------
+
+// observe keeps track of the authentication state from successful requests
+func (manager *tokenManager) observe(method string, params []any, result *RawResponse) {
+	switch method {
+	case "signin", "signup":
+		token, _ := result.ResultString()
+
+		manager.lock.Lock()
+		if len(params) > 0 {
+			manager.credentials = params[0]
+		}
+		manager.lock.Unlock()
+
+		manager.authenticated(token)
+
+	case "authenticate":
+		if len(params) > 0 {
+			token, _ := params[0].(string)
+			manager.authenticated(token)
+		}
+
+	case "invalidate":
+		manager.lock.Lock()
+		manager.credentials = nil
+		manager.generation++
+		manager.stopTimer()
+		manager.lock.Unlock()
+	}
+}
+
+// authenticated schedules the next refresh, based on when the token expires
+func (manager *tokenManager) authenticated(token string) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.generation++
+	manager.stopTimer()
+
+	if token == "" || manager.stopped {
+		return
+	}
+
+	data, err := TokenData{}.FromToken(token)
+	if err != nil || data.ExpiresAt == 0 {
+		return
+	}
+
+	remaining := time.Until(time.Unix(int64(data.ExpiresAt), 0))
+	delay := remaining - manager.config.Leeway
+	if delay <= 0 {
+		// the token doesn't live longer than our leeway, so we refresh half way instead
+		delay = remaining / 2
+	}
+	if delay <= 0 {
+		return
+	}
+
+	manager.schedule(delay, data.ExpiresAt)
+}
+
+// schedule refreshes the token after delay, the caller must hold the lock
+func (manager *tokenManager) schedule(delay time.Duration, expiresAt int) {
+	generation := manager.generation
+
+	manager.timer = time.AfterFunc(delay, func() {
+		_, err := manager.refresh(context.Background(), generation)
+		if err == nil {
+			return
+		}
+
+		manager.lock.Lock()
+		defer manager.lock.Unlock()
+
+		// nothing changed since we failed, so try again whilst the token is still valid
+		if manager.generation != generation || manager.stopped {
+			return
+		}
+		if time.Until(time.Unix(int64(expiresAt), 0)) > manager.config.RetryDelay {
+			manager.schedule(manager.config.RetryDelay, expiresAt)
+		}
+	})
+}
+
+// refresh re-authenticates, unless that already happened since generation
+// It returns true when the session changed, so a failed request is worth retrying
+func (manager *tokenManager) refresh(ctx context.Context, generation uint64) (bool, error) {
+	manager.refreshing.Lock()
+	defer manager.refreshing.Unlock()
+
+	manager.lock.Lock()
+	if manager.stopped {
+		manager.lock.Unlock()
+		return false, nil
+	}
+	if manager.generation != generation {
+		manager.lock.Unlock()
+		return true, nil
+	}
+	credentials := manager.credentials
+	manager.lock.Unlock()
+
+	result := &AuthenticationResult{}
+	err := ErrNoCredentials
+
+	switch {
+	case manager.config.Refresh != nil:
+		var token string
+		if token, err = manager.config.Refresh(ctx); err == nil {
+			if _, err = manager.db.send(ctx, "authenticate", token); err == nil {
+				err = result.fromToken(token)
+			}
+		}
+
+	case credentials != nil:
+		var response *RawResponse
+		if response, err = manager.db.send(ctx, "signin", credentials); err == nil {
+			err = result.fromQuery(response)
+		}
+	}
+
+	if manager.config.OnRefresh != nil {
+		if err != nil {
+			manager.config.OnRefresh(nil, err)
+		} else {
+			manager.config.OnRefresh(result, nil)
+		}
+	}
+
+	// root users don't receive a token we can decode, but signing in again still worked
+	if err == ErrInvalidLoginResponse && manager.config.Refresh == nil {
+		err = nil
+	}
+
+	return err == nil, err
+}
+
+func (manager *tokenManager) current() uint64 {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	return manager.generation
+}
+
+func (manager *tokenManager) stop() {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.stopped = true
+	manager.stopTimer()
+}
+
+// stopTimer the caller must hold the lock
+func (manager *tokenManager) stopTimer() {
+	if manager.timer != nil {
+		manager.timer.Stop()
+		manager.timer = nil
+	}
+}
+
+// authMethods are never retried, as they're the ones used to refresh the session
+var authMethods = map[string]bool{
+	"signin":       true,
+	"signup":       true,
+	"authenticate": true,
+	"invalidate":   true,
+}
+
+// isAuthError checks if the request failed because the session is no longer authenticated
+func isAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(internal.ClassifyError(err), &authErr)
+}
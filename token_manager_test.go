@@ -0,0 +1,161 @@
+package surrealdb_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	"github.com/test-go/testify/require"
+)
+
+// testToken creates an unsigned jwt with the given claims
+func testToken(claims map[string]any) string {
+	payload, _ := json.Marshal(claims)
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"HS512"}`))
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func TestDB_TokenRefreshSignsInBeforeExpiry(t *testing.T) {
+	var lock sync.Mutex
+	signins := 0
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		if req.Method != "signin" {
+			return nil, ""
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		signins++
+
+		return testToken(map[string]any{
+			"exp": time.Now().Add(2 * time.Second).Unix(),
+			"ns":  "test",
+			"db":  "test",
+			"sc":  "account",
+			"id":  "user:1",
+		}), ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	refreshed := make(chan *surrealdb.AuthenticationResult, 1)
+	db.EnableTokenRefresh(surrealdb.TokenRefreshConfig{
+		Leeway: 1500 * time.Millisecond,
+		OnRefresh: func(result *surrealdb.AuthenticationResult, err error) {
+			require.NoError(t, err)
+			refreshed <- result
+		},
+	})
+
+	result, err := db.SigninUser(surrealdb.UserInfo{User: "sam", Password: "secret", Scope: "account"})
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	require.Equal(t, "user:1", result.Id)
+
+	select {
+	case result := <-refreshed:
+		require.True(t, result.Success)
+		require.NotEmpty(t, result.Token)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the token was not refreshed")
+	}
+
+	requests := mock.requests[0]
+	require.Equal(t, []string{"signin", "signin"}, mock.methods(0))
+	require.Equal(t, string(requests[0].Params[0]), string(requests[1].Params[0]))
+}
+
+func TestDB_TokenRefreshRetriesAfterAuthError(t *testing.T) {
+	var lock sync.Mutex
+	expired := true
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch req.Method {
+		case "authenticate":
+			expired = false
+		case "query":
+			if expired {
+				return nil, "There was a problem with the database: The token has expired"
+			}
+			return []any{}, ""
+		}
+		return nil, ""
+	})
+
+	db, err := surrealdb.New(mock.config())
+	require.NoError(t, err)
+	defer db.Close()
+
+	db.EnableTokenRefresh(surrealdb.TokenRefreshConfig{
+		Refresh: func(ctx context.Context) (string, error) {
+			return testToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix()}), nil
+		},
+	})
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"query", "authenticate", "query"}, mock.methods(0))
+
+	// once disabled, nothing is retried
+	lock.Lock()
+	expired = true
+	lock.Unlock()
+
+	db.DisableTokenRefresh()
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "token has expired"))
+	require.Equal(t, []string{"query", "authenticate", "query", "query"}, mock.methods(0))
+}
+
+func TestDB_TokenRefreshUsesTheAutoLoginCredentials(t *testing.T) {
+	var lock sync.Mutex
+	signins := 0
+	mock := newMockServer(t, func(req mockRequest) (any, string) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch req.Method {
+		case "signin":
+			signins++
+			return testToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix()}), ""
+		case "query":
+			// the token from AutoLogin has expired, the one from refreshing hasn't
+			if signins < 2 {
+				return nil, "There was a problem with the database: The token has expired"
+			}
+			return []any{}, ""
+		}
+		return nil, ""
+	})
+
+	config := mock.config()
+	config.Username = "root"
+	config.Password = "root"
+	config.AutoLogin = true
+
+	db, err := surrealdb.New(config)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// enabled after AutoLogin already signed in
+	db.EnableTokenRefresh(surrealdb.TokenRefreshConfig{})
+
+	_, err = db.Query("SELECT * FROM user", map[string]any{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"signin", "query", "signin", "query"}, mock.methods(0))
+
+	requests := mock.requests[0]
+	require.Equal(t, string(requests[0].Params[0]), string(requests[2].Params[0]))
+}
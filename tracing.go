@@ -0,0 +1,58 @@
+package surrealdb
+
+import (
+	"context"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+)
+
+// Span is the part of an OpenTelemetry span the TracingObserver uses
+// Wrap a trace.Span to set its attributes, record errors and end it
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans, like an OpenTelemetry trace.Tracer
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracingObserver starts a span for every request, named after its method like surrealdb.query
+// The span is a child of the span in the context the request was made with(see the *Ctx methods)
+type TracingObserver struct {
+	Config.NopObserver
+	tracer Tracer
+}
+
+// NewTracingObserver creates an observer which starts the spans through tracer
+func NewTracingObserver(tracer Tracer) *TracingObserver {
+	return &TracingObserver{tracer: tracer}
+}
+
+type spanKey struct{}
+
+func (observer *TracingObserver) OnRequestStart(ctx context.Context, req Config.RequestStart) context.Context {
+	ctx, span := observer.tracer.Start(ctx, "surrealdb."+req.Method)
+
+	// the names follow the OpenTelemetry semantic conventions for databases
+	span.SetAttribute("db.system", "surrealdb")
+	span.SetAttribute("db.operation", req.Method)
+	span.SetAttribute("db.surrealdb.request_id", req.ID)
+	span.SetAttribute("db.surrealdb.param_size", req.ParamSize)
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (observer *TracingObserver) OnRequestEnd(ctx context.Context, req Config.RequestEnd) {
+	span, ok := ctx.Value(spanKey{}).(Span)
+	if !ok {
+		return
+	}
+
+	if req.Err != nil {
+		span.RecordError(req.Err)
+	}
+	span.End()
+}
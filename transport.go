@@ -0,0 +1,58 @@
+package surrealdb
+
+import (
+	"context"
+	"net/url"
+
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
+
+var (
+	// ErrUnsupportedMethod is returned when the transport has no way of handling an rpc method
+	ErrUnsupportedMethod = internal.ErrUnsupportedMethod
+)
+
+// RawResponse holds the undecoded response for a request, the resolvers decode it into the typed results
+type RawResponse = internal.RPCRawResponse
+
+// NewRawResponse creates a RawResponse from the raw rpc response data, for example:
+// {"id":"1","result":[{"id":"user:bob","username":"bob"}]}
+func NewRawResponse(data []byte) *RawResponse {
+	return internal.CreateRPCRawResponse(data)
+}
+
+// Transport is the connection DB uses to talk to the database
+type Transport interface {
+	// Send sends the request and receives its response, waiting until ctx is done
+	Send(ctx context.Context, id string, method string, params []any) (*RawResponse, error)
+	// Close closes the connection, any outstanding requests are failed
+	Close() error
+}
+
+// sessionTransport is implemented by transports that need to replay the session state
+// when their connection is re-established
+type sessionTransport interface {
+	SetSession(key string, method string, params []any)
+	RemoveSession(key string)
+}
+
+// NewWebsocketTransport creates the websocket transport, using the rpc endpoint
+func NewWebsocketTransport(config *Config.DbConfig) (Transport, error) {
+	return internal.NewWebsocket(config)
+}
+
+// NewHTTPTransport creates a transport which uses the http endpoints, useful when a websocket can't be kept open
+// It supports query, select, create, update, change, delete, signin, signup, authenticate, invalidate, use and let
+func NewHTTPTransport(config *Config.DbConfig) (Transport, error) {
+	return internal.NewHTTP(config)
+}
+
+// newTransport picks the transport based on the url scheme, http(s) will use the http transport
+func newTransport(config *Config.DbConfig) (Transport, error) {
+	if u, err := url.Parse(config.Url); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return NewHTTPTransport(config)
+	}
+
+	return NewWebsocketTransport(config)
+}
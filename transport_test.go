@@ -0,0 +1,131 @@
+package surrealdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	Config "github.com/idevelopthings/surrealdb.go.unofficial/config"
+	"github.com/test-go/testify/require"
+)
+
+func TestHTTPTransport_Query(t *testing.T) {
+	var sqlBody string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/signin":
+			w.Write([]byte(`{"code":200,"details":"Authentication succeeded","token":"the.jwt.token"}`))
+		case "/sql":
+			require.Equal(t, "Bearer the.jwt.token", r.Header.Get("Authorization"))
+			require.Equal(t, "test", r.Header.Get("NS"))
+			require.Equal(t, "app", r.Header.Get("DB"))
+
+			body, _ := io.ReadAll(r.Body)
+			sqlBody = string(body)
+
+			w.Write([]byte(`[
+				{"time":"1µs","status":"OK","result":null},
+				{"time":"10µs","status":"OK","result":[{"username":"bob"}]}
+			]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	db, err := surrealdb.New(&Config.DbConfig{
+		Url:       server.URL,
+		Username:  "root",
+		Password:  "root",
+		Namespace: "test",
+		Database:  "app",
+		AutoLogin: true,
+		AutoUse:   true,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	result := surrealdb.QueryWithConfig[testUserInformation](surrealdb.QueryConfig{
+		Db:     db,
+		Query:  "SELECT * FROM user WHERE username = $username",
+		Params: map[string]any{"username": "bob"},
+	})
+
+	require.NoError(t, result.Error())
+	require.Equal(t, "bob", result.First().Username)
+	require.Len(t, result.Results(), 1)
+	require.True(t, strings.HasPrefix(sqlBody, "LET $username = \"bob\";\n"))
+}
+
+func TestHTTPTransport_KeyEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/key/user/bob", r.URL.Path)
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`[{"time":"1µs","status":"OK","result":[{"username":"bob"}]}]`))
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code":403,"details":"Authentication failed","information":"You don't have permission to perform this query type"}`))
+		}
+	}))
+	defer server.Close()
+
+	db, err := surrealdb.New(&Config.DbConfig{Url: server.URL})
+	require.NoError(t, err)
+	defer db.Close()
+
+	result, err := db.SendCtx(context.Background(), "select", "user:bob")
+	require.NoError(t, err)
+	require.Equal(t, `[{"username":"bob"}]`, string(result.(*surrealdb.RawResponse).Result().Result))
+
+	_, err = db.SendCtx(context.Background(), "delete", "user:bob")
+	require.EqualError(t, err, "You don't have permission to perform this query type")
+
+	_, err = db.SendCtx(context.Background(), "live", "user")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), surrealdb.ErrUnsupportedMethod.Error())
+}
+
+func TestHTTPTransport_CloseFailsRequests(t *testing.T) {
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the query hangs until the client gives up on it, which is only noticed once the body has been read
+		io.ReadAll(r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	db, err := surrealdb.New(&Config.DbConfig{Url: server.URL})
+	require.NoError(t, err)
+
+	failed := make(chan error, 1)
+	go func() {
+		failed <- surrealdb.QueryOn[testUserInformation](db, "SELECT * FROM user").Error()
+	}()
+	<-started
+
+	require.NoError(t, db.Close())
+
+	select {
+	case err := <-failed:
+		require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the in-flight request wasn't failed by Close")
+	}
+
+	// requests sent after closing don't reach the server
+	_, err = db.Use("test", "test")
+	require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+	err = surrealdb.QueryOn[testUserInformation](db, "SELECT * FROM user").Error()
+	require.True(t, errors.Is(err, surrealdb.ErrConnectionClosed), err)
+	require.Len(t, started, 0)
+}
@@ -1,10 +1,10 @@
 package surrealdb
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"strings"
+
+	"github.com/goccy/go-json"
 )
 
 var (
@@ -16,16 +16,50 @@ type Patch struct {
 	Op    string `json:"op"`
 	Path  string `json:"path"`
 	Value any    `json:"value"`
+	// From is used by the "move" and "copy" operations
+	From string `json:"from,omitempty"`
 }
 
-// UserInfo TODO: A way to make User and Password use different names via configuration
-// This method only works if your scope is configured with those namings also, otherwise auth will fail
+// UserInfo holds the credentials used to sign in/sign up
+// User and Password are sent as "user" and "pass", unless UserField/PasswordField are set
+// to match the variables your scope uses, for scopes with more variables see SigninScope
 type UserInfo struct {
 	User      string `json:"user"`
 	Password  string `json:"pass"`
 	Namespace string `json:"NS,omitempty"`
 	Database  string `json:"DB,omitempty"`
 	Scope     string `json:"SC,omitempty"`
+
+	// UserField is the name of the variable User is sent as, defaults to "user"
+	UserField string `json:"-"`
+	// PasswordField is the name of the variable Password is sent as, defaults to "pass"
+	PasswordField string `json:"-"`
+}
+
+func (info UserInfo) MarshalJSON() ([]byte, error) {
+	userField, passwordField := info.UserField, info.PasswordField
+	if userField == "" {
+		userField = "user"
+	}
+	if passwordField == "" {
+		passwordField = "pass"
+	}
+
+	vars := map[string]any{
+		userField:     info.User,
+		passwordField: info.Password,
+	}
+	if info.Namespace != "" {
+		vars["NS"] = info.Namespace
+	}
+	if info.Database != "" {
+		vars["DB"] = info.Database
+	}
+	if info.Scope != "" {
+		vars["SC"] = info.Scope
+	}
+
+	return json.Marshal(vars)
 }
 
 type AuthenticationResult struct {
@@ -35,20 +69,27 @@ type AuthenticationResult struct {
 	TokenData
 }
 
-func (data *AuthenticationResult) fromQuery(result any) error {
-	if result == nil || result == "" {
+func (data *AuthenticationResult) fromQuery(result *RawResponse) error {
+	if result == nil || result.HasError() {
 		return ErrInvalidLoginResponse
 	}
-	if _, ok := result.(string); !ok {
+
+	token, ok := result.ResultString()
+	if !ok || token == "" {
 		return ErrInvalidLoginResponse
 	}
-	tokenData, err := TokenData{}.FromToken(result.(string))
+
+	return data.fromToken(token)
+}
+
+func (data *AuthenticationResult) fromToken(token string) error {
+	tokenData, err := TokenData{}.FromToken(token)
 	if err != nil {
 		return err
 	}
 
 	data.Success = true
-	data.Token = result.(string)
+	data.Token = token
 	data.TokenData = tokenData
 
 	return nil
@@ -65,6 +106,7 @@ type TokenData struct {
 	Id        string `json:"id"`
 }
 
+// FromToken decodes the claims of a jwt, without validating it, see TokenParser to validate tokens
 func (token TokenData) FromToken(tokenString string) (TokenData, error) {
 	data := TokenData{}
 
@@ -78,7 +120,7 @@ func (token TokenData) FromToken(tokenString string) (TokenData, error) {
 	}
 
 	// Decode the payload
-	payload, err := base64.RawStdEncoding.DecodeString(segments[1])
+	payload, err := decodeTokenSegment(segments[1])
 	if err != nil {
 		return data, err
 	}
@@ -97,4 +139,11 @@ type ResultQuery[T any] struct {
 	Result []T    `json:"result"`
 	Status string `json:"status"`
 	Time   string `json:"time"`
+	// Err is set when the statement failed, Result is empty when it is
+	Err *StatementError `json:"-"`
+}
+
+// HasError Check if the statement failed
+func (result *ResultQuery[T]) HasError() bool {
+	return result.Err != nil
 }
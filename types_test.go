@@ -1,9 +1,15 @@
 package surrealdb_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"testing"
+	"time"
 
 	"github.com/idevelopthings/surrealdb.go.unofficial"
 	"github.com/test-go/testify/suite"
@@ -60,3 +66,73 @@ func (suite *TestTypesTestSuite) Test_ValidToken() {
 	suite.Require().Equal("user:ywjeahn8cv4krcx29km5", tokenData.Id)
 	suite.Require().Equal("SurrealDB", tokenData.Issuer)
 }
+
+// signedToken creates a jwt signed with HS256/HS384/HS512
+func signedToken(algorithm string, key []byte, claims map[string]any) string {
+	hashes := map[string]func() hash.Hash{"HS256": sha256.New, "HS384": sha512.New384, "HS512": sha512.New}
+
+	header, _ := json.Marshal(map[string]any{"typ": "JWT", "alg": algorithm})
+	payload, _ := json.Marshal(claims)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(hashes[algorithm], key)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (suite *TestTypesTestSuite) Test_TokenUsesBase64Url() {
+	// once encoded, this payload contains "_" and "-", which only exist in base64url
+	token := signedToken("HS512", []byte("secret"), map[string]any{"id": "user:???", "sc": ">>>"})
+
+	tokenData, err := surrealdb.TokenData{}.FromToken(token)
+	suite.Require().NoError(err)
+	suite.Require().Equal("user:???", tokenData.Id)
+	suite.Require().Equal(">>>", tokenData.Scope)
+}
+
+func (suite *TestTypesTestSuite) Test_TokenParserValidatesTime() {
+	now := time.Unix(1700000000, 0)
+	parser := surrealdb.TokenParser{Now: func() time.Time { return now }}
+
+	_, err := parser.Parse(signedToken("HS512", nil, map[string]any{"exp": now.Unix() + 60, "nbf": now.Unix() - 60}))
+	suite.Require().NoError(err)
+
+	token, err := parser.Parse(signedToken("HS512", nil, map[string]any{"exp": now.Unix() - 10, "id": "user:1"}))
+	suite.Require().Equal(surrealdb.ErrTokenExpired, err)
+	suite.Require().Equal("user:1", token.Claims.Id)
+
+	_, err = parser.Parse(signedToken("HS512", nil, map[string]any{"nbf": now.Unix() + 10}))
+	suite.Require().Equal(surrealdb.ErrTokenNotValidYet, err)
+
+	parser.ClockSkew = 30 * time.Second
+
+	_, err = parser.Parse(signedToken("HS512", nil, map[string]any{"exp": now.Unix() - 10}))
+	suite.Require().NoError(err)
+	_, err = parser.Parse(signedToken("HS512", nil, map[string]any{"nbf": now.Unix() + 10}))
+	suite.Require().NoError(err)
+}
+
+func (suite *TestTypesTestSuite) Test_TokenParserVerifiesSignature() {
+	claims := map[string]any{"exp": time.Now().Add(time.Hour).Unix(), "id": "user:1"}
+
+	for _, algorithm := range []string{"HS256", "HS384", "HS512"} {
+		parser := surrealdb.TokenParser{Key: []byte("secret")}
+
+		token, err := parser.Parse(signedToken(algorithm, []byte("secret"), claims))
+		suite.Require().NoError(err, algorithm)
+		suite.Require().Equal(algorithm, token.Header.Algorithm)
+		suite.Require().Equal("user:1", token.Claims.Id)
+
+		_, err = parser.Parse(signedToken(algorithm, []byte("another secret"), claims))
+		suite.Require().Equal(surrealdb.ErrTokenInvalidSignature, err, algorithm)
+	}
+
+	parser := surrealdb.TokenParser{Key: []byte("secret"), Algorithms: []string{"HS512"}}
+	_, err := parser.Parse(signedToken("HS256", []byte("secret"), claims))
+	suite.Require().Equal(surrealdb.ErrTokenAlgorithm, err)
+
+	_, err = parser.Parse("eyJhbGciOiJub25lIn0.e30.")
+	suite.Require().Equal(surrealdb.ErrTokenAlgorithm, err)
+}
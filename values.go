@@ -0,0 +1,228 @@
+package surrealdb
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/idevelopthings/surrealdb.go.unofficial/internal"
+)
+
+// These types are sent as SurrealDB's own types with the cbor protocol(see Config.ProtocolCBOR), and the same way
+// SurrealDB writes them as json with the json protocol. Datetimes are a time.Time
+// Responses are decoded from json with both protocols, so a value only gets its type back when it's decoded
+// into one of these, decoded into any or a string it's the same string the json protocol returns
+
+var (
+	// ErrInvalidRecordID is returned when a string isn't a record id
+	ErrInvalidRecordID = errors.New("invalid record id")
+	// ErrInvalidDecimal is returned when a decimal isn't a number
+	ErrInvalidDecimal = errors.New("invalid decimal")
+)
+
+// RecordID is the id of a record, like user:bob
+type RecordID struct {
+	Table string
+	// ID is a string, number, array or object
+	ID any
+}
+
+// NewRecordID creates the id of a record in table
+func NewRecordID(table string, id any) RecordID {
+	return RecordID{Table: table, ID: id}
+}
+
+// ParseRecordID parses a record id like user:bob, user:⟨bob smith⟩, user:123 or user:[1, 2]
+func ParseRecordID(value string) (RecordID, error) {
+	table, rest, _, ok := cutRecordIDPart(value)
+	if !ok || table == "" || !strings.HasPrefix(rest, ":") || len(rest) == 1 {
+		return RecordID{}, ErrInvalidRecordID
+	}
+
+	id, rest, escaped, ok := cutRecordIDPart(rest[1:])
+	if !ok || rest != "" {
+		return RecordID{}, ErrInvalidRecordID
+	}
+
+	record := RecordID{Table: table, ID: id}
+
+	// escaped ids are always strings, otherwise it can be a number, array or object
+	if escaped {
+		return record, nil
+	}
+
+	switch {
+	case strings.HasPrefix(id, "[") || strings.HasPrefix(id, "{"):
+		var decoded any
+		decoder := json.NewDecoder(strings.NewReader(id))
+		decoder.UseNumber()
+		if err := decoder.Decode(&decoded); err != nil {
+			return RecordID{}, ErrInvalidRecordID
+		}
+		record.ID = decoded
+
+	default:
+		if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+			record.ID = n
+		}
+	}
+
+	return record, nil
+}
+
+// cutRecordIDPart cuts the table or id from the start of value, removing the escaping around it
+func cutRecordIDPart(value string) (part string, rest string, escaped bool, ok bool) {
+	switch {
+	case strings.HasPrefix(value, "⟨"):
+		var unescaped strings.Builder
+		for rest := value[len("⟨"):]; rest != ""; {
+			switch {
+			case strings.HasPrefix(rest, `\⟩`):
+				unescaped.WriteString("⟩")
+				rest = rest[len(`\⟩`):]
+			case strings.HasPrefix(rest, "⟩"):
+				return unescaped.String(), rest[len("⟩"):], true, true
+			default:
+				unescaped.WriteByte(rest[0])
+				rest = rest[1:]
+			}
+		}
+		return "", "", false, false
+
+	case strings.HasPrefix(value, "`"):
+		end := strings.Index(value[1:], "`")
+		if end < 0 {
+			return "", "", false, false
+		}
+		return value[1 : end+1], value[end+2:], true, true
+	}
+
+	// the table ends at the first colon, the id is everything after it
+	if end := strings.Index(value, ":"); end >= 0 {
+		return value[:end], value[end:], false, true
+	}
+
+	return value, "", false, true
+}
+
+func (id RecordID) String() string {
+	return internal.FormatRecordID(id.Table, id.ID)
+}
+
+func (id RecordID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.String())
+}
+
+func (id *RecordID) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := ParseRecordID(value)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+
+	return nil
+}
+
+// CBORTag is used by the cbor protocol, so the id is sent as a record id rather than a string
+func (id RecordID) CBORTag() (uint64, any) {
+	return internal.TagRecordID, []any{id.Table, id.ID}
+}
+
+// --------------------------------------------------
+
+// Duration is a SurrealDB duration, in json it's written like SurrealDB writes them, for example 1h30m or 2w
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return internal.FormatDuration(time.Duration(d))
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts a SurrealDB duration string, or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		var nanoseconds int64
+		if json.Unmarshal(data, &nanoseconds) != nil {
+			return err
+		}
+		*d = Duration(nanoseconds)
+		return nil
+	}
+
+	parsed, err := internal.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// CBORTag is used by the cbor protocol, so the duration is sent as a duration rather than a string
+func (d Duration) CBORTag() (uint64, any) {
+	return internal.TagDuration, []any{int64(time.Duration(d) / time.Second), int64(time.Duration(d) % time.Second)}
+}
+
+// --------------------------------------------------
+
+// Decimal is an arbitrary precision number, it's kept as text so no precision is lost
+type Decimal string
+
+// Float64 converts the decimal into a float, which may lose precision
+func (d Decimal) Float64() (float64, error) {
+	value, err := strconv.ParseFloat(string(d), 64)
+	if err != nil {
+		return 0, ErrInvalidDecimal
+	}
+
+	return value, nil
+}
+
+func (d Decimal) String() string {
+	return string(d)
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if !json.Valid([]byte(d)) || strings.ContainsAny(string(d), `"[{tfn`) {
+		return nil, ErrInvalidDecimal
+	}
+
+	return []byte(d), nil
+}
+
+// UnmarshalJSON accepts a number, or a string containing a number
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		data = []byte(value)
+	}
+
+	if _, err := strconv.ParseFloat(string(data), 64); err != nil {
+		return ErrInvalidDecimal
+	}
+	*d = Decimal(data)
+
+	return nil
+}
+
+// CBORTag is used by the cbor protocol, so the decimal is sent as a decimal rather than a float
+func (d Decimal) CBORTag() (uint64, any) {
+	return internal.TagDecimal, string(d)
+}
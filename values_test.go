@@ -0,0 +1,60 @@
+package surrealdb_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/idevelopthings/surrealdb.go.unofficial"
+	"github.com/test-go/testify/require"
+)
+
+func TestRecordID_ParseAndFormat(t *testing.T) {
+	ids := map[string]surrealdb.RecordID{
+		"user:bob":                 {Table: "user", ID: "bob"},
+		"user:42":                  {Table: "user", ID: int64(42)},
+		"user:⟨42⟩":                {Table: "user", ID: "42"},
+		"user:⟨bob smith⟩":         {Table: "user", ID: "bob smith"},
+		`user:⟨a\⟩b⟩`:              {Table: "user", ID: "a⟩b"},
+		"⟨user-log⟩:bob":           {Table: "user-log", ID: "bob"},
+		`temperature:["London",3]`: {Table: "temperature", ID: []any{"London", json.Number("3")}},
+	}
+
+	for value, expected := range ids {
+		parsed, err := surrealdb.ParseRecordID(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, parsed, value)
+		require.Equal(t, value, parsed.String(), value)
+	}
+
+	for _, value := range []string{"user", ":bob", "user:", "user:⟨bob", "user:⟨bob⟩smith"} {
+		_, err := surrealdb.ParseRecordID(value)
+		require.True(t, errors.Is(err, surrealdb.ErrInvalidRecordID), value)
+	}
+}
+
+func TestValues_JSON(t *testing.T) {
+	type values struct {
+		ID       surrealdb.RecordID `json:"id"`
+		Lifetime surrealdb.Duration `json:"lifetime"`
+		Balance  surrealdb.Decimal  `json:"balance"`
+	}
+
+	encoded, err := json.Marshal(values{
+		ID:       surrealdb.NewRecordID("user", "bob"),
+		Lifetime: surrealdb.Duration(9*24*time.Hour + 90*time.Minute),
+		Balance:  "0.1000000000000000000001",
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"user:bob","lifetime":"1w2d1h30m","balance":0.1000000000000000000001}`, string(encoded))
+
+	var decoded values
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"user:bob","lifetime":"1h500ms","balance":"12.50"}`), &decoded))
+	require.Equal(t, surrealdb.NewRecordID("user", "bob"), decoded.ID)
+	require.Equal(t, surrealdb.Duration(time.Hour+500*time.Millisecond), decoded.Lifetime)
+	require.Equal(t, surrealdb.Decimal("12.50"), decoded.Balance)
+
+	_, err = json.Marshal(surrealdb.Decimal("lots"))
+	require.Error(t, err)
+}